@@ -1,12 +1,12 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,33 +32,201 @@ type TaxEvent struct {
 	AverageCostBasisPerUnitInUSD *big.Rat
 	LongTerm                     bool
 	AcquisitionDates             map[string]bool
+
+	// DisallowedLoss and ReplacementLotID are set when this sale triggers
+	// the IRS §1091 wash-sale rule: a loss within WashSaleWindow of a
+	// replacement purchase of the same currency. The disallowed amount is
+	// rolled into the replacement lot's cost basis instead.
+	DisallowedLoss   *big.Rat
+	ReplacementLotID string
+}
+
+// OrdinaryIncomeEvent records currency received as income (staking
+// rewards, interest, dividends) rather than bought, valued at its spot
+// price on the date received. It's reported separately from capital
+// gains/losses.
+type OrdinaryIncomeEvent struct {
+	Date       string
+	Currency   string
+	Amount     *big.Rat
+	ValueInUSD *big.Rat
+	Tags       string
 }
 
 type Holding struct {
+	ID                    string
 	Currency              string
 	Amount                *big.Rat
 	CostBasisPerUnitInUSD *big.Rat
 	AcquisitionDate       time.Time
 	Tags                  string
+	Account               string
 }
 
+// DefaultAccount is the account Buy/Sell operate against when no transfer
+// has ever moved a holding elsewhere.
+const DefaultAccount = ""
+
 type HoldingDB struct {
-	Holdings  []*Holding
-	Balances  map[string]*big.Rat
-	TaxEvents map[string][]*TaxEvent
+	Holdings []*Holding
+	// Balances is account -> currency -> amount held. Buy/Sell only ever
+	// touch DefaultAccount; Transfer moves balances (and the underlying
+	// Holdings) between accounts.
+	Balances       map[string]map[string]*big.Rat
+	TaxEvents      map[string][]*TaxEvent
+	OrdinaryIncome map[string][]*OrdinaryIncomeEvent
+
+	// DefaultLotSelector is used by Sell when no per-currency override is
+	// set in LotSelectors. It defaults to FIFO.
+	DefaultLotSelector LotSelector
+	LotSelectors       map[string]LotSelector
+
+	// WashSaleWindow overrides DefaultWashSaleWindow when non-zero.
+	WashSaleWindow time.Duration
+
+	// Oracle, if set, supplies a USD price for Buy/Sell/Trade/Dividend
+	// rows that omit one.
+	Oracle PriceOracle
+
+	// Lenient, if true, auto-registers an unknown currency into this
+	// instance's known-currency set instead of rejecting it with
+	// ErrUnknownCurrency.
+	Lenient bool
+
+	nextLotID        int
+	pendingWashSales []*pendingWashSaleLoss
+
+	// knownCurrencies starts as a copy of the package-level KnownCurrencies
+	// and is what Lenient actually registers into, so a lenient Validate
+	// dry-run (see clone) can never leak a currency into other HoldingDBs.
+	knownCurrencies map[string]bool
 }
 
 func NewHoldingDB() *HoldingDB {
 	db := &HoldingDB{
-		Balances:  map[string]*big.Rat{},
-		TaxEvents: map[string][]*TaxEvent{},
+		Balances:           map[string]map[string]*big.Rat{DefaultAccount: {}},
+		TaxEvents:          map[string][]*TaxEvent{},
+		OrdinaryIncome:     map[string][]*OrdinaryIncomeEvent{},
+		DefaultLotSelector: FIFOSelector{},
+		LotSelectors:       map[string]LotSelector{},
+		knownCurrencies:    map[string]bool{},
 	}
 	for currency := range KnownCurrencies {
-		db.Balances[currency] = big.NewRat(0, 1)
+		db.Balances[DefaultAccount][currency] = big.NewRat(0, 1)
+		db.knownCurrencies[currency] = true
 	}
 	return db
 }
 
+// checkCurrency reports whether currency is usable, auto-registering it
+// into h's own known-currency set when h.Lenient instead of rejecting it.
+func (h *HoldingDB) checkCurrency(currency string) error {
+	if h.knownCurrencies[currency] {
+		return nil
+	}
+	if h.Lenient {
+		h.knownCurrencies[currency] = true
+		return nil
+	}
+	return ErrUnknownCurrency{Currency: currency}
+}
+
+// clone returns a deep copy of h's mutable ledger state, sharing its
+// read-only configuration (LotSelectors, Oracle, ...). It's used by
+// Validate to replay a CSV against the current ledger without ever
+// mutating h itself.
+func (h *HoldingDB) clone() *HoldingDB {
+	clone := &HoldingDB{
+		Balances:           map[string]map[string]*big.Rat{},
+		TaxEvents:          map[string][]*TaxEvent{},
+		OrdinaryIncome:     map[string][]*OrdinaryIncomeEvent{},
+		DefaultLotSelector: h.DefaultLotSelector,
+		LotSelectors:       h.LotSelectors,
+		WashSaleWindow:     h.WashSaleWindow,
+		Oracle:             h.Oracle,
+		Lenient:            h.Lenient,
+		nextLotID:          h.nextLotID,
+		knownCurrencies:    map[string]bool{},
+	}
+	for currency := range h.knownCurrencies {
+		clone.knownCurrencies[currency] = true
+	}
+	for _, holding := range h.Holdings {
+		clone.Holdings = append(clone.Holdings, &Holding{
+			ID:                    holding.ID,
+			Currency:              holding.Currency,
+			Amount:                new(big.Rat).Set(holding.Amount),
+			CostBasisPerUnitInUSD: new(big.Rat).Set(holding.CostBasisPerUnitInUSD),
+			AcquisitionDate:       holding.AcquisitionDate,
+			Tags:                  holding.Tags,
+			Account:               holding.Account,
+		})
+	}
+	for account, byCurrency := range h.Balances {
+		clone.Balances[account] = map[string]*big.Rat{}
+		for currency, balance := range byCurrency {
+			clone.Balances[account][currency] = new(big.Rat).Set(balance)
+		}
+	}
+	// pendingWashSales is copied (with its own TaxEvent/loss copies, since
+	// a match during the scratch replay calls disallowLoss, which mutates
+	// the event and the replacement holding's basis) so a loss sale still
+	// awaiting a replacement purchase in the live ledger can be matched
+	// against one appearing later in the validated CSV.
+	for _, pending := range h.pendingWashSales {
+		eventCopy := *pending.event
+		clone.pendingWashSales = append(clone.pendingWashSales, &pendingWashSaleLoss{
+			event:    &eventCopy,
+			currency: pending.currency,
+			saleDate: pending.saleDate,
+			loss:     new(big.Rat).Set(pending.loss),
+		})
+	}
+	return clone
+}
+
+// balanceFor returns (creating if necessary) the balance of currency in
+// account.
+func (h *HoldingDB) balanceFor(account, currency string) *big.Rat {
+	byCurrency, ok := h.Balances[account]
+	if !ok {
+		byCurrency = map[string]*big.Rat{}
+		h.Balances[account] = byCurrency
+	}
+	balance, ok := byCurrency[currency]
+	if !ok {
+		balance = big.NewRat(0, 1)
+		byCurrency[currency] = balance
+	}
+	return balance
+}
+
+// priceInUSD resolves currency's spot USD price on date via Oracle, for
+// callers that were given no explicit price.
+func (h *HoldingDB) priceInUSD(currency string, date time.Time) (*big.Rat, error) {
+	if h.Oracle == nil {
+		return nil, fmt.Errorf("no USD price given for %s on %s and no PriceOracle configured", currency, date.Format("2006-01-02"))
+	}
+	return h.Oracle.PriceInUSD(currency, date)
+}
+
+// SetLotSelector configures the LotSelector Sell uses to satisfy sales of
+// currency. Pass "" for currency to change the run-wide default.
+func (h *HoldingDB) SetLotSelector(currency string, selector LotSelector) {
+	if currency == "" {
+		h.DefaultLotSelector = selector
+		return
+	}
+	h.LotSelectors[currency] = selector
+}
+
+func (h *HoldingDB) lotSelectorFor(currency string) LotSelector {
+	if selector, ok := h.LotSelectors[currency]; ok {
+		return selector
+	}
+	return h.DefaultLotSelector
+}
+
 func parseTime(date string) (time.Time, error) {
 	if strings.Contains(date, "/") {
 		return time.Parse("2006/01/02", date)
@@ -66,154 +234,355 @@ func parseTime(date string) (time.Time, error) {
 	return time.Parse("2006-01-02", date)
 }
 
-func (h *HoldingDB) Buy(currency, amount, costBasisPerUnitInUSD, acquisitionDate, tags string) {
-	if !KnownCurrencies[currency] {
-		panic("unknown currency")
+// Buy records a new lot. If lotID is empty, one is generated so the lot can
+// still be targeted later by a SpecificIDSelector. If costBasisPerUnitInUSD
+// is empty, it's resolved via Oracle.
+func (h *HoldingDB) Buy(currency, amount, costBasisPerUnitInUSD, acquisitionDate, tags, lotID string) (*Holding, error) {
+	if err := h.checkCurrency(currency); err != nil {
+		return nil, err
 	}
 	date, err := parseTime(acquisitionDate)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if costBasisPerUnitInUSD == "" {
+		price, err := h.priceInUSD(currency, date)
+		if err != nil {
+			return nil, err
+		}
+		costBasisPerUnitInUSD = price.String()
+	}
+	amountVal, err := parseRat("Amount", amount)
+	if err != nil {
+		return nil, err
+	}
+	costBasisVal, err := parseRat("Unit basis", costBasisPerUnitInUSD)
+	if err != nil {
+		return nil, err
+	}
+	if lotID == "" {
+		h.nextLotID++
+		lotID = fmt.Sprintf("%s-%d", currency, h.nextLotID)
 	}
 	holding := &Holding{
+		ID:                    lotID,
 		Currency:              currency,
-		Amount:                must(new(big.Rat).SetString(amount)),
-		CostBasisPerUnitInUSD: must(new(big.Rat).SetString(costBasisPerUnitInUSD)),
+		Amount:                amountVal,
+		CostBasisPerUnitInUSD: costBasisVal,
 		AcquisitionDate:       date,
 		Tags:                  tags,
+		Account:               DefaultAccount,
 	}
 
 	h.Holdings = append(h.Holdings, holding)
-	h.Balances[currency].Add(h.Balances[currency], holding.Amount)
+	balance := h.balanceFor(DefaultAccount, currency)
+	balance.Add(balance, holding.Amount)
+	h.checkWashSaleOnBuy(holding)
+	return holding, nil
 }
 
-func (h *HoldingDB) Sell(currency, amount, salePricePerUnitInUSD, saleDate, tags string) {
-	if !KnownCurrencies[currency] {
-		panic("unknown currency")
+// Dividend records amount units of currency received as income (staking
+// rewards, interest, airdrops) rather than bought. It buys a lot at the
+// Oracle's spot price on receiptDate, establishing that price as the
+// lot's basis so the income isn't taxed again on a later sale, and logs
+// an OrdinaryIncomeEvent for the FMV received.
+func (h *HoldingDB) Dividend(currency, amount, receiptDate, tags string) error {
+	if err := h.checkCurrency(currency); err != nil {
+		return err
 	}
-	date, err := parseTime(saleDate)
+	date, err := parseTime(receiptDate)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	price, err := h.priceInUSD(currency, date)
+	if err != nil {
+		return err
 	}
 
-	var amountVal, salePrice big.Rat
-	must(amountVal.SetString(amount))
-	must(salePrice.SetString(salePricePerUnitInUSD))
-
-	h.Balances[currency].Sub(h.Balances[currency], &amountVal)
-	if h.Balances[currency].Sign() < 0 {
-		panic("negative balance")
+	if _, err := h.Buy(currency, amount, price.String(), receiptDate, tags, ""); err != nil {
+		return err
 	}
 
-	sort.Sort(HoldingsByCurrencyAndDate{currency, h.Holdings})
+	amountVal, err := parseRat("Amount", amount)
+	if err != nil {
+		return err
+	}
+	h.OrdinaryIncome[receiptDate] = append(h.OrdinaryIncome[receiptDate], &OrdinaryIncomeEvent{
+		Date:       receiptDate,
+		Currency:   currency,
+		Amount:     amountVal,
+		ValueInUSD: new(big.Rat).Mul(amountVal, price),
+		Tags:       tags,
+	})
+	return nil
+}
 
-	remainingAmountVal := new(big.Rat).Set(&amountVal)
-	var longTermCostBasisSum, shortTermCostBasisSum big.Rat
-	var longTermAmount, shortTermAmount big.Rat
-	longTermAcquisitionDates := map[string]bool{}
-	shortTermAcquisitionDates := map[string]bool{}
+// Sell disposes of amount units of currency held in DefaultAccount,
+// consuming lots via the configured LotSelector for currency (see
+// SetLotSelector). Passing one or more lotIDs overrides that selector for
+// this call with Specific Identification, pulling from exactly those lots
+// in the order given.
+func (h *HoldingDB) Sell(currency, amount, salePricePerUnitInUSD, saleDate, tags string, lotIDs ...string) error {
+	return h.sellFromAccount(DefaultAccount, currency, amount, salePricePerUnitInUSD, saleDate, tags, lotIDs...)
+}
 
-	for remainingAmountVal.Sign() > 0 {
-		if len(h.Holdings) <= 0 {
-			panic("no more holdings")
+// sellFromAccount is Sell's implementation, scoped to a single account so
+// Transfer can capitalize its fee as a disposition of exactly the lots
+// it's moving out of source instead of letting the configured LotSelector
+// reach into whatever account happens to hold the oldest (or cheapest,
+// ...) lot of currency.
+func (h *HoldingDB) sellFromAccount(account, currency, amount, salePricePerUnitInUSD, saleDate, tags string, lotIDs ...string) error {
+	if err := h.checkCurrency(currency); err != nil {
+		return err
+	}
+	date, err := parseTime(saleDate)
+	if err != nil {
+		return err
+	}
+	if salePricePerUnitInUSD == "" {
+		price, err := h.priceInUSD(currency, date)
+		if err != nil {
+			return err
 		}
-		next := h.Holdings[len(h.Holdings)-1]
-		if next.Currency != currency {
-			panic("no more holdings of currency")
+		salePricePerUnitInUSD = price.String()
+	}
+
+	amountVal, err := parseRat("Amount", amount)
+	if err != nil {
+		return err
+	}
+	salePrice, err := parseRat("Unit price", salePricePerUnitInUSD)
+	if err != nil {
+		return err
+	}
+
+	matching := make([]*Holding, 0, len(h.Holdings))
+	for _, holding := range h.Holdings {
+		if holding.Currency == currency && holding.Account == account {
+			matching = append(matching, holding)
 		}
+	}
 
-		if less(remainingAmountVal, next.Amount) {
-			// this one has more than enough to cover it
-			next.Amount.Sub(next.Amount, remainingAmountVal)
-			if next.Amount.Sign() < 0 {
-				panic("error!")
-			}
+	selector := h.lotSelectorFor(currency)
+	if len(lotIDs) > 0 {
+		selector = SpecificIDSelector{IDs: lotIDs}
+	}
 
-			costBasisSum := new(big.Rat).Mul(next.CostBasisPerUnitInUSD, remainingAmountVal)
-			if isLongTerm(next.AcquisitionDate, date) {
-				longTermCostBasisSum.Add(&longTermCostBasisSum, costBasisSum)
-				longTermAmount.Add(&longTermAmount, remainingAmountVal)
-				longTermAcquisitionDates[next.AcquisitionDate.Format("2006-01-02")] = true
-			} else {
-				shortTermCostBasisSum.Add(&shortTermCostBasisSum, costBasisSum)
-				shortTermAmount.Add(&shortTermAmount, remainingAmountVal)
-				shortTermAcquisitionDates[next.AcquisitionDate.Format("2006-01-02")] = true
-			}
+	consumptions, err := selector.Select(currency, amountVal, matching)
+	if err != nil {
+		return err
+	}
 
-			break
+	var longTermCostBasisSum, shortTermCostBasisSum big.Rat
+	var longTermAmount, shortTermAmount big.Rat
+	longTermAcquisitionDates := map[string]bool{}
+	shortTermAcquisitionDates := map[string]bool{}
+	soldLotIDs := map[string]bool{}
+
+	for _, consumption := range consumptions {
+		soldLotIDs[consumption.Holding.ID] = true
+		next := consumption.Holding
+		balance := h.balanceFor(next.Account, currency)
+		balance.Sub(balance, consumption.Amount)
+		if balance.Sign() < 0 {
+			return ErrNegativeBalance{Account: next.Account, Currency: currency}
 		}
-
-		remainingAmountVal.Sub(remainingAmountVal, next.Amount)
-		costBasisSum := new(big.Rat).Mul(next.CostBasisPerUnitInUSD, next.Amount)
+		costBasisSum := new(big.Rat).Mul(next.CostBasisPerUnitInUSD, consumption.Amount)
 		if isLongTerm(next.AcquisitionDate, date) {
 			longTermCostBasisSum.Add(&longTermCostBasisSum, costBasisSum)
-			longTermAmount.Add(&longTermAmount, next.Amount)
+			longTermAmount.Add(&longTermAmount, consumption.Amount)
 			longTermAcquisitionDates[next.AcquisitionDate.Format("2006-01-02")] = true
 		} else {
 			shortTermCostBasisSum.Add(&shortTermCostBasisSum, costBasisSum)
-			shortTermAmount.Add(&shortTermAmount, next.Amount)
+			shortTermAmount.Add(&shortTermAmount, consumption.Amount)
 			shortTermAcquisitionDates[next.AcquisitionDate.Format("2006-01-02")] = true
 		}
-		h.Holdings = h.Holdings[:len(h.Holdings)-1]
+		next.Amount.Sub(next.Amount, consumption.Amount)
+		if next.Amount.Sign() < 0 {
+			// A LotSelector consumed more of a lot than it held.
+			return ErrNegativeBalance{Account: next.Account, Currency: currency}
+		}
 	}
-	if remainingAmountVal.Sign() < 0 {
-		panic("error!")
+
+	remainingHoldings := h.Holdings[:0]
+	for _, holding := range h.Holdings {
+		if holding.Amount.Sign() > 0 {
+			remainingHoldings = append(remainingHoldings, holding)
+		}
 	}
+	h.Holdings = remainingHoldings
 
 	if longTermAmount.Sign() > 0 {
-		h.TaxEvents[saleDate] = append(h.TaxEvents[saleDate], &TaxEvent{
+		event := &TaxEvent{
 			Date:                         saleDate,
 			Amount:                       &longTermAmount,
 			Currency:                     currency,
-			SalePricePerUnitInUSD:        &salePrice,
+			SalePricePerUnitInUSD:        salePrice,
 			AverageCostBasisPerUnitInUSD: new(big.Rat).Quo(&longTermCostBasisSum, &longTermAmount),
 			LongTerm:                     true,
 			AcquisitionDates:             longTermAcquisitionDates,
-		})
+		}
+		h.TaxEvents[saleDate] = append(h.TaxEvents[saleDate], event)
+		h.checkWashSaleOnSell(event, date, soldLotIDs)
 	}
 	if shortTermAmount.Sign() > 0 {
-		h.TaxEvents[saleDate] = append(h.TaxEvents[saleDate], &TaxEvent{
+		event := &TaxEvent{
 			Date:                         saleDate,
 			Amount:                       &shortTermAmount,
 			Currency:                     currency,
-			SalePricePerUnitInUSD:        &salePrice,
+			SalePricePerUnitInUSD:        salePrice,
 			AverageCostBasisPerUnitInUSD: new(big.Rat).Quo(&shortTermCostBasisSum, &shortTermAmount),
 			LongTerm:                     false,
 			AcquisitionDates:             shortTermAcquisitionDates,
+		}
+		h.TaxEvents[saleDate] = append(h.TaxEvents[saleDate], event)
+		h.checkWashSaleOnSell(event, date, soldLotIDs)
+	}
+
+	return nil
+}
+
+// Transfer moves amount units of currency from source to target (account
+// labels, e.g. exchange/wallet names), preserving each lot's original
+// acquisition date and cost basis so long-term status isn't reset. fee
+// units of currency are capitalized as a disposition at market price
+// (via Oracle if not resolvable otherwise), since paying a network/
+// exchange fee in-kind is a taxable event like any other sale.
+func (h *HoldingDB) Transfer(currency, amount, source, target, fee, date string) error {
+	if err := h.checkCurrency(currency); err != nil {
+		return err
+	}
+	if _, err := parseTime(date); err != nil {
+		return err
+	}
+
+	amountVal, err := parseRat("Amount", amount)
+	if err != nil {
+		return err
+	}
+
+	matching := make([]*Holding, 0, len(h.Holdings))
+	for _, holding := range h.Holdings {
+		if holding.Currency == currency && holding.Account == source {
+			matching = append(matching, holding)
+		}
+	}
+
+	consumptions, err := h.lotSelectorFor(currency).Select(currency, amountVal, matching)
+	if err != nil {
+		return err
+	}
+
+	for _, consumption := range consumptions {
+		holding := consumption.Holding
+
+		sourceBalance := h.balanceFor(source, currency)
+		sourceBalance.Sub(sourceBalance, consumption.Amount)
+		if sourceBalance.Sign() < 0 {
+			return ErrNegativeBalance{Account: source, Currency: currency}
+		}
+		targetBalance := h.balanceFor(target, currency)
+		targetBalance.Add(targetBalance, consumption.Amount)
+
+		if consumption.Amount.Cmp(holding.Amount) == 0 {
+			holding.Account = target
+			continue
+		}
+		holding.Amount.Sub(holding.Amount, consumption.Amount)
+		h.Holdings = append(h.Holdings, &Holding{
+			ID:                    holding.ID,
+			Currency:              currency,
+			Amount:                new(big.Rat).Set(consumption.Amount),
+			CostBasisPerUnitInUSD: new(big.Rat).Set(holding.CostBasisPerUnitInUSD),
+			AcquisitionDate:       holding.AcquisitionDate,
+			Tags:                  holding.Tags,
+			Account:               target,
 		})
 	}
 
+	if fee != "" {
+		feeVal, ok := new(big.Rat).SetString(fee)
+		if !ok {
+			return ErrMalformedRow{Column: "Fees (in addition to Amount)", Reason: fmt.Sprintf("invalid number %q", fee)}
+		}
+		if feeVal.Sign() < 0 {
+			return ErrMalformedRow{Column: "Fees (in addition to Amount)", Reason: fmt.Sprintf("negative fee %q", fee)}
+		}
+		if feeVal.Sign() > 0 {
+			return h.sellFromAccount(source, currency, fee, "", date, "transfer-fee")
+		}
+	}
+	return nil
 }
 
+// Trade disposes of amount1 units of currency1 for amount2 units of
+// currency2. Exactly one of sourceCurrencyPricePerUnitInUSD/
+// targetCurrencyPricePerUnitInUSD should be given; the other leg's price
+// is implied by the exchange rate. If neither is given, Oracle prices the
+// source leg directly.
 func (h *HoldingDB) Trade(currency1, currency2, amount1, amount2,
 	sourceCurrencyPricePerUnitInUSD, targetCurrencyPricePerUnitInUSD,
-	tradeDate string) {
+	tradeDate string) error {
+	if sourceCurrencyPricePerUnitInUSD == "" && targetCurrencyPricePerUnitInUSD == "" {
+		date, err := parseTime(tradeDate)
+		if err != nil {
+			return err
+		}
+		price, err := h.priceInUSD(currency1, date)
+		if err != nil {
+			return err
+		}
+		sourceCurrencyPricePerUnitInUSD = price.String()
+	}
 	if sourceCurrencyPricePerUnitInUSD != "" {
 		if targetCurrencyPricePerUnitInUSD != "" {
-			panic("needs only one currency price")
+			return fmt.Errorf("trade: needs only one currency price")
 		}
 
-		var a1, p, a2, d big.Rat
-		must(a1.SetString(amount1))
-		must(p.SetString(sourceCurrencyPricePerUnitInUSD))
-		must(a2.SetString(amount2))
-		d.Quo(new(big.Rat).Mul(&a1, &p), &a2)
+		a1, err := parseRat("Amount", amount1)
+		if err != nil {
+			return err
+		}
+		p, err := parseRat("Unit price", sourceCurrencyPricePerUnitInUSD)
+		if err != nil {
+			return err
+		}
+		a2, err := parseRat("Target amount", amount2)
+		if err != nil {
+			return err
+		}
+		d := new(big.Rat).Quo(new(big.Rat).Mul(a1, p), a2)
 
-		h.Sell(currency1, amount1, sourceCurrencyPricePerUnitInUSD, tradeDate, "trade-to-"+currency2)
-		h.Buy(currency2, amount2, d.String(), tradeDate, "trade-from-"+currency1)
-	} else {
-		if targetCurrencyPricePerUnitInUSD == "" {
-			panic("needs currency price")
+		if err := h.Sell(currency1, amount1, sourceCurrencyPricePerUnitInUSD, tradeDate, "trade-to-"+currency2); err != nil {
+			return err
 		}
+		_, err = h.Buy(currency2, amount2, d.String(), tradeDate, "trade-from-"+currency1, "")
+		return err
+	}
 
-		var a1, p, a2, d big.Rat
-		must(a2.SetString(amount2))
-		must(p.SetString(targetCurrencyPricePerUnitInUSD))
-		must(a1.SetString(amount1))
-		d.Quo(new(big.Rat).Mul(&a2, &p), &a1)
+	if targetCurrencyPricePerUnitInUSD == "" {
+		return fmt.Errorf("trade: needs currency price")
+	}
+
+	a2, err := parseRat("Target amount", amount2)
+	if err != nil {
+		return err
+	}
+	p, err := parseRat("Target unit price", targetCurrencyPricePerUnitInUSD)
+	if err != nil {
+		return err
+	}
+	a1, err := parseRat("Amount", amount1)
+	if err != nil {
+		return err
+	}
+	d := new(big.Rat).Quo(new(big.Rat).Mul(a2, p), a1)
 
-		h.Sell(currency1, amount1, d.String(), tradeDate, "trade-to-"+currency2)
-		h.Buy(currency2, amount2, targetCurrencyPricePerUnitInUSD, tradeDate, "trade-from-"+currency1)
+	if err := h.Sell(currency1, amount1, d.String(), tradeDate, "trade-to-"+currency2); err != nil {
+		return err
 	}
+	_, err = h.Buy(currency2, amount2, targetCurrencyPricePerUnitInUSD, tradeDate, "trade-from-"+currency1, "")
+	return err
 }
 
 func rowEqual(x, y []string) bool {
@@ -228,114 +597,115 @@ func rowEqual(x, y []string) bool {
 	return true
 }
 
+// LoadCSV loads the repo's own CSV format directly. It's equivalent to
+// running CSVImporter{}.Import through LoadEvents, kept around because
+// it's the format most callers (and main, absent --format) still use.
 func (h *HoldingDB) LoadCSV(r io.Reader) error {
-	source := csv.NewReader(r)
-
-	header1, err := source.Read()
+	events, err := (CSVImporter{}).Import(r)
 	if err != nil {
 		return err
 	}
+	return h.LoadEvents(events)
+}
 
-	if !rowEqual(header1, strings.Split(",Buy,,,,,Trades,,,,,,,Transfers,,,,,,,Sales,,,,,,", ",")) {
-		return fmt.Errorf("malformed csv")
-	}
+func main() {
+	db := NewHoldingDB()
 
-	header2, err := source.Read()
-	if err != nil {
-		return err
+	formatFlag := ""
+	reportFlag := ""
+	yearFlag := 0
+	validateFlag := false
+	path := ""
+	for _, arg := range os.Args[1:] {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			formatFlag = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "--report="):
+			reportFlag = strings.TrimPrefix(arg, "--report=")
+		case strings.HasPrefix(arg, "--year="):
+			year, err := strconv.Atoi(strings.TrimPrefix(arg, "--year="))
+			if err != nil {
+				panic(err)
+			}
+			yearFlag = year
+		case arg == "--strict":
+			db.Lenient = false
+		case arg == "--lenient":
+			db.Lenient = true
+		case arg == "--validate":
+			validateFlag = true
+		default:
+			path = arg
+		}
 	}
 
-	if !rowEqual(header2, strings.Split(",Amount,Currency,Unit basis,USD Value,,Amount,Source currency,Amount,Target currency,Unit price,Target amount after fees,,Amount,Currency,Source,Target,Fees (in addition to Amount),,,Amount,Currency,Unit price,Fees (in addition to Amount),USD Net,,URL", ",")) {
-		return fmt.Errorf("malformed csv")
+	if path == "" {
+		fmt.Printf("usage: %s [--format=csv|ofx|coinbase|kraken|gemini] [--report=8949|txf|summary] [--year=YYYY] [--strict|--lenient] [--validate] <file>\n", os.Args[0])
+		os.Exit(1)
 	}
 
-	for {
-		row, err := source.Read()
+	if validateFlag {
+		if formatFlag != "" && formatFlag != "csv" {
+			fmt.Fprintf(os.Stderr, "--validate only supports this repo's own CSV format (got --format=%s)\n", formatFlag)
+			os.Exit(1)
+		}
+		fh, err := os.Open(path)
 		if err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return err
+			panic(err)
 		}
+		defer fh.Close()
 
-		date := row[0]
-		if date == "" {
-			return fmt.Errorf("invalid date")
+		errs := db.Validate(fh)
+		if len(errs) == 0 {
+			fmt.Println("OK: no errors found")
+			return
 		}
-
-		rowType := ""
-
-		// buy?
-		{
-			amount := cleanAmount(row[1])
-			currency := row[2]
-			unitbasis := cleanAmount(row[3])
-
-			if amount != "" || currency != "" || unitbasis != "" {
-				h.Buy(currency, amount, unitbasis, date, "")
-				if rowType != "" {
-					panic("row double duty")
-				}
-				rowType = "buy"
-			}
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
 		}
+		os.Exit(1)
+	}
 
-		// trade?
-		{
-			sourceAmount := cleanAmount(row[6])
-			sourceCurrency := row[7]
-			targetAmount := cleanAmount(row[8])
-			targetCurrency := row[9]
-			sourceUnitPrice := cleanAmount(row[10])
-			targetAmountAfterFees := cleanAmount(row[11])
-
-			if sourceAmount != "" || sourceCurrency != "" || sourceUnitPrice != "" ||
-				targetAmount != "" || targetCurrency != "" || targetAmountAfterFees != "" {
-				h.Trade(sourceCurrency, targetCurrency, sourceAmount, targetAmount, sourceUnitPrice, "", date)
-				if rowType != "" {
-					panic("row double duty")
-				}
-				rowType = "trade"
-			}
+	var importer Importer
+	if formatFlag != "" {
+		imp, ok := ImporterByName(formatFlag)
+		if !ok {
+			panic(fmt.Sprintf("unknown --format %q", formatFlag))
 		}
-
-		// sell?
-		{
-			amount := cleanAmount(row[20])
-			currency := row[21]
-			unitPrice := cleanAmount(row[22])
-			fees := cleanAmount(row[23])
-
-			if amount != "" || currency != "" || unitPrice != "" || fees != "" {
-				h.Sell(currency, amount, unitPrice, date, "")
-				if rowType != "" {
-					panic("row double duty")
-				}
-				rowType = "sell"
-			}
+		importer = imp
+	} else {
+		imp, ok := ImporterForFile(path)
+		if !ok {
+			panic(fmt.Sprintf("can't infer importer for %q; pass --format", path))
 		}
-	}
-}
-
-func main() {
-	db := NewHoldingDB()
-
-	if len(os.Args) <= 1 {
-		fmt.Printf("usage: %s <trades.csv>\n", os.Args[0])
-		os.Exit(1)
+		importer = imp
 	}
 
-	fh, err := os.Open(os.Args[1])
+	fh, err := os.Open(path)
 	if err != nil {
 		panic(err)
 	}
-	err = db.LoadCSV(fh)
+	events, err := importer.Import(fh)
 	if err != nil {
 		panic(err)
 	}
+	if err := db.LoadEvents(events); err != nil {
+		panic(err)
+	}
 	fh.Close()
 
-	fmt.Println("Description\tDate acquired\tDate sold\tProceeds\tCost Basis\tUnit price\tUnit basis\tGain (or loss)\tTerm\n")
+	if reportFlag != "" {
+		reporter, ok := ReporterByName(reportFlag)
+		if !ok {
+			panic(fmt.Sprintf("unknown --report %q", reportFlag))
+		}
+		if err := reporter.Report(os.Stdout, db, yearFlag); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	fmt.Println("Description\tDate acquired\tDate sold\tProceeds\tCost Basis\tUnit price\tUnit basis\tCode\tAdjustment\tGain (or loss)\tTerm")
 	for _, date := range sortedEvents(db.TaxEvents, false) {
 		byCurrency := map[string][]*TaxEvent{}
 		for _, event := range db.TaxEvents[date] {
@@ -352,7 +722,7 @@ func main() {
 			}
 
 			for _, msg := range sortedEvents(byLongTerm, false) {
-				var salesPriceSum, costBasisSum, amount big.Rat
+				var salesPriceSum, costBasisSum, amount, disallowedLossSum big.Rat
 
 				acquisitionDates := map[string]bool{}
 				for _, event := range byLongTerm[msg] {
@@ -362,26 +732,79 @@ func main() {
 						new(big.Rat).Mul(event.Amount, event.AverageCostBasisPerUnitInUSD))
 					amount.Add(&amount, event.Amount)
 					acquisitionDates = setUnion(acquisitionDates, event.AcquisitionDates)
+					if event.DisallowedLoss != nil {
+						disallowedLossSum.Add(&disallowedLossSum, event.DisallowedLoss)
+					}
+				}
+
+				adjCode := ""
+				if disallowedLossSum.Sign() != 0 {
+					adjCode = "W"
 				}
+				gainOrLoss := new(big.Rat).Sub(&salesPriceSum, &costBasisSum)
+				gainOrLoss.Add(gainOrLoss, &disallowedLossSum)
 
-				fmt.Printf("%s %s\t%s\t%s\t$%s\t$%s\t$%s\t$%s\t$%s\t%s\n",
+				fmt.Printf("%s %s\t%s\t%s\t$%s\t$%s\t$%s\t$%s\t%s\t$%s\t$%s\t%s\n",
 					format(&amount), currency,
 					dateRange(setToStrings(acquisitionDates)), date,
 					salesPriceSum.FloatString(2), costBasisSum.FloatString(2),
 					new(big.Rat).Quo(&salesPriceSum, &amount).FloatString(2),
 					new(big.Rat).Quo(&costBasisSum, &amount).FloatString(2),
-					new(big.Rat).Sub(&salesPriceSum, &costBasisSum).FloatString(2), msg)
+					adjCode, disallowedLossSum.FloatString(2),
+					gainOrLoss.FloatString(2), msg)
+			}
+		}
+		fmt.Println()
+	}
+
+	if len(db.OrdinaryIncome) > 0 {
+		fmt.Println("Ordinary income:")
+		fmt.Println("Date\tCurrency\tAmount\tValue (USD)\tNotes")
+		for _, date := range sortedIncomeDates(db.OrdinaryIncome, false) {
+			for _, income := range db.OrdinaryIncome[date] {
+				fmt.Printf("%s\t%s\t%s\t$%s\t%s\n",
+					income.Date, income.Currency, format(income.Amount),
+					income.ValueInUSD.FloatString(2), income.Tags)
 			}
 		}
 		fmt.Println()
 	}
 
 	fmt.Println("Balances:")
-	for _, currency := range sortedCurrencies(db.Balances, false) {
-		fmt.Println(" ", currency, db.Balances[currency].FloatString(2))
+	for _, account := range sortedAccounts(db.Balances) {
+		label := account
+		if label == DefaultAccount {
+			label = "(default)"
+		}
+		fmt.Println(" ", label+":")
+		for _, currency := range sortedCurrencies(db.Balances[account], false) {
+			fmt.Println("   ", currency, db.Balances[account][currency].FloatString(2))
+		}
 	}
 }
 
+func sortedAccounts(balances map[string]map[string]*big.Rat) (rv []string) {
+	rv = make([]string, 0, len(balances))
+	for account := range balances {
+		rv = append(rv, account)
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+func sortedIncomeDates(income map[string][]*OrdinaryIncomeEvent, reverse bool) (rv []string) {
+	rv = make([]string, 0, len(income))
+	for key := range income {
+		rv = append(rv, key)
+	}
+	if reverse {
+		sort.Sort(sort.Reverse(sort.StringSlice(rv)))
+	} else {
+		sort.Strings(rv)
+	}
+	return rv
+}
+
 func sortedCurrencies(balances map[string]*big.Rat, reverse bool) (rv []string) {
 	rv = make([]string, 0, len(balances))
 	for currency := range balances {
@@ -408,34 +831,14 @@ func sortedEvents(events map[string][]*TaxEvent, reverse bool) (rv []string) {
 	return rv
 }
 
-func must(a *big.Rat, ok bool) *big.Rat {
+// parseRat parses s as a decimal, returning an ErrMalformedRow naming
+// column if it isn't a valid number.
+func parseRat(column, s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
 	if !ok {
-		panic("failed")
+		return nil, ErrMalformedRow{Column: column, Reason: fmt.Sprintf("invalid number %q", s)}
 	}
-	return a
-}
-
-type HoldingsByCurrencyAndCostBasis struct {
-	currency string
-	holdings []*Holding
-}
-
-func (h HoldingsByCurrencyAndCostBasis) Len() int { return len(h.holdings) }
-
-func (h HoldingsByCurrencyAndCostBasis) Swap(i, j int) {
-	h.holdings[i], h.holdings[j] = h.holdings[j], h.holdings[i]
-}
-
-func (h HoldingsByCurrencyAndCostBasis) Less(i, j int) bool {
-	if h.holdings[i].Currency != h.currency {
-		return true
-	}
-	if h.holdings[j].Currency != h.currency {
-		return false
-	}
-	return less(
-		h.holdings[i].CostBasisPerUnitInUSD,
-		h.holdings[j].CostBasisPerUnitInUSD)
+	return r, nil
 }
 
 func less(a, b *big.Rat) bool {
@@ -455,27 +858,6 @@ func isLongTerm(acquisitionDate, saleDate time.Time) bool {
 	return saleDate.Sub(acquisitionDate) > 366*24*time.Hour
 }
 
-type HoldingsByCurrencyAndDate struct {
-	currency string
-	holdings []*Holding
-}
-
-func (h HoldingsByCurrencyAndDate) Len() int { return len(h.holdings) }
-
-func (h HoldingsByCurrencyAndDate) Swap(i, j int) {
-	h.holdings[i], h.holdings[j] = h.holdings[j], h.holdings[i]
-}
-
-func (h HoldingsByCurrencyAndDate) Less(i, j int) bool {
-	if h.holdings[i].Currency != h.currency {
-		return true
-	}
-	if h.holdings[j].Currency != h.currency {
-		return false
-	}
-	return h.holdings[i].AcquisitionDate.Before(h.holdings[j].AcquisitionDate)
-}
-
 func setToStrings(set map[string]bool) []string {
 	rv := make([]string, 0, len(set))
 	for key := range set {
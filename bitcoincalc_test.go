@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransferMovesBalancePreservingAcquisitionDateAndBasis(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "10", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Transfer("BTC", "4", DefaultAccount, "coinbase", "", "2023-01-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := db.balanceFor(DefaultAccount, "BTC"); got.Cmp(mustRat(t, "6")) != 0 {
+		t.Fatalf("default account balance = %s, want 6", got.FloatString(8))
+	}
+	if got := db.balanceFor("coinbase", "BTC"); got.Cmp(mustRat(t, "4")) != 0 {
+		t.Fatalf("coinbase account balance = %s, want 4", got.FloatString(8))
+	}
+
+	var moved *Holding
+	for _, h := range db.Holdings {
+		if h.Account == "coinbase" {
+			moved = h
+		}
+	}
+	if moved == nil {
+		t.Fatal("no holding landed in the coinbase account")
+	}
+	if moved.AcquisitionDate.Format("2006-01-02") != "2020-01-01" {
+		t.Fatalf("transferred lot's acquisition date = %s, want 2020-01-01 (long-term status must survive the transfer)",
+			moved.AcquisitionDate.Format("2006-01-02"))
+	}
+	if moved.CostBasisPerUnitInUSD.Cmp(mustRat(t, "100")) != 0 {
+		t.Fatalf("transferred lot's basis = %s, want 100", moved.CostBasisPerUnitInUSD.FloatString(8))
+	}
+}
+
+func TestTransferCapitalizesFeeAsDisposalFromSourceAccount(t *testing.T) {
+	db := NewHoldingDB()
+	oracle := NewCachedPriceOracle()
+	saleDate, err := parseTime("2023-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oracle.Set("BTC", saleDate, mustRat(t, "20000"))
+	db.Oracle = oracle
+
+	if _, err := db.Buy("BTC", "10", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Transfer("BTC", "4", DefaultAccount, "coinbase", "0.1", "2023-01-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 4 transferred + 0.1 fee sold out of the default account's 10.
+	if got := db.balanceFor(DefaultAccount, "BTC"); got.Cmp(mustRat(t, "5.9")) != 0 {
+		t.Fatalf("default account balance = %s, want 5.9", got.FloatString(8))
+	}
+	if got := db.balanceFor("coinbase", "BTC"); got.Cmp(mustRat(t, "4")) != 0 {
+		t.Fatalf("coinbase account balance = %s, want 4 (unaffected by the fee)", got.FloatString(8))
+	}
+
+	events := db.TaxEvents["2023-01-01"]
+	if len(events) != 1 {
+		t.Fatalf("got %d tax events for the fee disposition, want 1: %+v", len(events), events)
+	}
+	if events[0].Amount.Cmp(mustRat(t, "0.1")) != 0 {
+		t.Fatalf("fee disposition amount = %s, want 0.1", events[0].Amount.FloatString(8))
+	}
+}
+
+// TestTransferRejectsMalformedFee is a regression test: a fee that fails
+// to parse must be reported as ErrMalformedRow, not silently dropped.
+func TestTransferRejectsMalformedFee(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "10", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.Transfer("BTC", "4", DefaultAccount, "coinbase", "not-a-number", "2023-01-01")
+	var malformed ErrMalformedRow
+	if !errors.As(err, &malformed) {
+		t.Fatalf("got err %v, want an ErrMalformedRow", err)
+	}
+	if malformed.Column != "Fees (in addition to Amount)" {
+		t.Fatalf("got Column %q, want %q", malformed.Column, "Fees (in addition to Amount)")
+	}
+}
+
+// TestTransferRejectsNegativeFee covers the same contract for a fee that
+// parses but is negative.
+func TestTransferRejectsNegativeFee(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "10", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.Transfer("BTC", "4", DefaultAccount, "coinbase", "-1", "2023-01-01")
+	var malformed ErrMalformedRow
+	if !errors.As(err, &malformed) {
+		t.Fatalf("got err %v, want an ErrMalformedRow", err)
+	}
+}
+
+// TestSellOnlyDrainsDefaultAccountAfterTransfer is a regression test: Sell
+// (and Trade, which calls it) must never pull lots out of an account a
+// Transfer has moved them into, even when the configured LotSelector
+// would otherwise prefer those lots (e.g. FIFO preferring an older lot
+// sitting in a non-default account).
+func TestSellOnlyDrainsDefaultAccountAfterTransfer(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "10", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Transfer("BTC", "10", DefaultAccount, "coinbase", "", "2023-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "5", "200", "2023-06-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Sell("BTC", "5", "300", "2023-07-01", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := db.balanceFor(DefaultAccount, "BTC"); got.Sign() != 0 {
+		t.Fatalf("default account balance = %s, want 0 (the sale should have emptied it)", got.FloatString(8))
+	}
+	if got := db.balanceFor("coinbase", "BTC"); got.Cmp(mustRat(t, "10")) != 0 {
+		t.Fatalf("coinbase account balance = %s, want 10 (untouched by Sell)", got.FloatString(8))
+	}
+}
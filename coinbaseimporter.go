@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// CoinbaseImporter reads a Coinbase "Transaction History" CSV export.
+// Only transaction types that map cleanly onto Buy/Sell are handled;
+// fiat deposits/withdrawals and unrecognized rows are skipped.
+type CoinbaseImporter struct{}
+
+var coinbaseHeader = "Timestamp"
+
+func (CoinbaseImporter) Import(r io.Reader) ([]Event, error) {
+	source := csv.NewReader(r)
+	source.FieldsPerRecord = -1
+
+	if err := skipToHeader(source, coinbaseHeader); err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for {
+		row, err := source.Read()
+		if err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return nil, err
+		}
+		if len(row) < 6 {
+			return nil, fmt.Errorf("coinbase csv: malformed row %v", row)
+		}
+
+		date := dateOnly(row[0])
+		txType := row[1]
+		currency := row[2]
+		amount := row[3]
+		unitPrice := row[5]
+
+		switch txType {
+		case "Buy":
+			events = append(events, Event{
+				Kind: EventBuy, Date: date, Currency: currency,
+				Amount: amount, UnitPrice: unitPrice, Tags: "coinbase",
+			})
+		case "Receive", "Rewards Income", "Coinbase Earn", "Staking Income", "Learning Reward":
+			events = append(events, Event{
+				Kind: EventDividend, Date: date, Currency: currency,
+				Amount: amount, Tags: "coinbase-income",
+			})
+		case "Sell":
+			events = append(events, Event{
+				Kind: EventSell, Date: date, Currency: currency,
+				Amount: amount, UnitPrice: unitPrice, Tags: "coinbase",
+			})
+		case "Convert":
+			// The export doesn't break the target side of a Convert out
+			// into its own columns, so treat it as a disposition at spot
+			// price like any other sale.
+			events = append(events, Event{
+				Kind: EventSell, Date: date, Currency: currency,
+				Amount: amount, UnitPrice: unitPrice, Tags: "coinbase-convert",
+			})
+		case "Send":
+			events = append(events, Event{
+				Kind: EventSell, Date: date, Currency: currency,
+				Amount: amount, UnitPrice: unitPrice, Tags: "coinbase-send",
+			})
+		}
+	}
+}
+
+func dateOnly(ts string) string {
+	if len(ts) >= 10 {
+		return ts[0:10]
+	}
+	return ts
+}
+
+// skipToHeader advances source past any preamble rows an exchange export
+// prepends (account summaries, blank lines), stopping once a row's first
+// column matches want.
+func skipToHeader(source *csv.Reader, want string) error {
+	for {
+		row, err := source.Read()
+		if err != nil {
+			return err
+		}
+		if len(row) > 0 && row[0] == want {
+			return nil
+		}
+	}
+}
+
+func init() {
+	RegisterImporter("coinbase", CoinbaseImporter{})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoinbaseImporterMapsTransactionTypes(t *testing.T) {
+	csv := "Some account summary line\n" +
+		"\n" +
+		"Timestamp,Transaction Type,Asset,Quantity Transacted,Spot Price Currency,Spot Price at Transaction\n" +
+		"2023-01-01T00:00:00Z,Buy,BTC,1.5,USD,20000\n" +
+		"2023-02-01T00:00:00Z,Sell,BTC,0.5,USD,25000\n" +
+		"2023-03-01T00:00:00Z,Rewards Income,BTC,0.01,USD,\n"
+
+	events, err := (CoinbaseImporter{}).Import(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	if buy := events[0]; buy.Kind != EventBuy || buy.Date != "2023-01-01" || buy.Currency != "BTC" || buy.Amount != "1.5" || buy.UnitPrice != "20000" {
+		t.Errorf("unexpected buy event: %+v", buy)
+	}
+	if sell := events[1]; sell.Kind != EventSell || sell.Date != "2023-02-01" || sell.Amount != "0.5" {
+		t.Errorf("unexpected sell event: %+v", sell)
+	}
+	if income := events[2]; income.Kind != EventDividend || income.Amount != "0.01" {
+		t.Errorf("unexpected income event: %+v", income)
+	}
+}
+
+func TestCoinbaseImporterSkipsUnmappedTransactionTypes(t *testing.T) {
+	csv := "Timestamp,Transaction Type,Asset,Quantity Transacted,Spot Price Currency,Spot Price at Transaction\n" +
+		"2023-01-01T00:00:00Z,Deposit,USD,100,USD,1\n"
+
+	events, err := (CoinbaseImporter{}).Import(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("got %d events for an unmapped transaction type, want 0: %+v", len(events), events)
+	}
+}
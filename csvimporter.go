@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// CSVImporter reads this repo's own fixed-header CSV format: a Buy, a
+// Trade, a Transfer, and a Sale section side by side on the same row,
+// exactly one of which may be populated per row.
+type CSVImporter struct{}
+
+// Extra optional trailing columns, appended after URL, that let a row pin
+// a Buy to a specific Lot ID and a Sell to the specific lot(s) (separated
+// by ";") it should draw from via SpecificIDSelector.
+const csvLotIDHeader = ",Lot ID,Sell Lot ID(s)"
+
+const csvHeader2Base = ",Amount,Currency,Unit basis,USD Value,,Amount,Source currency,Amount,Target currency,Unit price,Target amount after fees,,Amount,Currency,Source,Target,Fees (in addition to Amount),,,Amount,Currency,Unit price,Fees (in addition to Amount),USD Net,,URL"
+
+// readCSVHeader validates the two fixed header rows and reports whether
+// the optional Lot ID columns are present.
+func readCSVHeader(source *csv.Reader) (hasLotIDColumns bool, err error) {
+	header1, err := source.Read()
+	if err != nil {
+		return false, err
+	}
+	if !rowEqual(header1, strings.Split(",Buy,,,,,Trades,,,,,,,Transfers,,,,,,,Sales,,,,,,", ",")) &&
+		!rowEqual(header1, strings.Split(",Buy,,,,,Trades,,,,,,,Transfers,,,,,,,Sales,,,,,,,,", ",")) {
+		return false, ErrMalformedRow{Row: 1, Column: "header", Reason: "unrecognized section header row"}
+	}
+
+	header2, err := source.Read()
+	if err != nil {
+		return false, err
+	}
+	hasLotIDColumns = rowEqual(header2, strings.Split(csvHeader2Base+csvLotIDHeader, ","))
+	if !hasLotIDColumns && !rowEqual(header2, strings.Split(csvHeader2Base, ",")) {
+		return false, ErrMalformedRow{Row: 2, Column: "header", Reason: "unrecognized column header row"}
+	}
+	return hasLotIDColumns, nil
+}
+
+// parseCSVRow turns one data row into zero or more Events. rowNum is
+// 1-based among data rows (the two header rows aren't counted), used only
+// for diagnostics.
+func parseCSVRow(rowNum int, row []string, hasLotIDColumns bool) ([]Event, error) {
+	date := row[0]
+	if date == "" {
+		return nil, ErrMalformedRow{Row: rowNum, Column: "Date", Reason: "missing date"}
+	}
+
+	lotID, sellLotIDs := "", ""
+	if hasLotIDColumns {
+		lotID = row[27]
+		sellLotIDs = row[28]
+	}
+
+	var events []Event
+	rowType := ""
+
+	if amount, currency, unitbasis := cleanAmount(row[1]), row[2], cleanAmount(row[3]); amount != "" || currency != "" || unitbasis != "" {
+		rowType = "Buy"
+		events = append(events, Event{
+			Kind: EventBuy, Date: date, Currency: currency,
+			Amount: amount, UnitPrice: unitbasis, LotID: lotID,
+		})
+	}
+
+	if sourceAmount, sourceCurrency, targetAmount, targetCurrency, sourceUnitPrice, targetAmountAfterFees :=
+		cleanAmount(row[6]), row[7], cleanAmount(row[8]), row[9], cleanAmount(row[10]), cleanAmount(row[11]); sourceAmount != "" || sourceCurrency != "" ||
+		sourceUnitPrice != "" || targetAmount != "" || targetCurrency != "" || targetAmountAfterFees != "" {
+		if rowType != "" {
+			return nil, ErrMalformedRow{Row: rowNum, Column: "Trade", Reason: "row already has a " + rowType + " entry"}
+		}
+		rowType = "Trade"
+		events = append(events, Event{
+			Kind: EventTrade, Date: date, Currency: sourceCurrency,
+			Amount: sourceAmount, UnitPrice: sourceUnitPrice,
+			TargetCurrency: targetCurrency, TargetAmount: targetAmount,
+		})
+	}
+
+	if amount, currency, source, target, fee := cleanAmount(row[13]), row[14], row[15], row[16], cleanAmount(row[17]); amount != "" || currency != "" || source != "" || target != "" || fee != "" {
+		if rowType != "" {
+			return nil, ErrMalformedRow{Row: rowNum, Column: "Transfer", Reason: "row already has a " + rowType + " entry"}
+		}
+		rowType = "Transfer"
+		events = append(events, Event{
+			Kind: EventTransfer, Date: date, Currency: currency,
+			Amount: amount, Source: source, Target: target, Fee: fee,
+		})
+	}
+
+	if amount, currency, unitPrice, fees := cleanAmount(row[20]), row[21], cleanAmount(row[22]), cleanAmount(row[23]); amount != "" || currency != "" || unitPrice != "" || fees != "" {
+		if rowType != "" {
+			return nil, ErrMalformedRow{Row: rowNum, Column: "Sale", Reason: "row already has a " + rowType + " entry"}
+		}
+		var lotIDs []string
+		if sellLotIDs != "" {
+			lotIDs = strings.Split(sellLotIDs, ";")
+		}
+		events = append(events, Event{
+			Kind: EventSell, Date: date, Currency: currency,
+			Amount: amount, UnitPrice: unitPrice, LotIDs: lotIDs,
+		})
+	}
+
+	return events, nil
+}
+
+func (CSVImporter) Import(r io.Reader) ([]Event, error) {
+	source := csv.NewReader(r)
+	source.FieldsPerRecord = -1
+
+	hasLotIDColumns, err := readCSVHeader(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	rowNum := 0
+	for {
+		row, err := source.Read()
+		if err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return nil, err
+		}
+		rowNum++
+
+		rowEvents, err := parseCSVRow(rowNum, row, hasLotIDColumns)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, rowEvents...)
+	}
+}
+
+// Validate streams r as this repo's CSV format against a scratch copy of
+// h's ledger (see HoldingDB.clone), reporting every malformed row and
+// every semantic error (unknown currency, negative balance) it finds
+// instead of stopping at the first, and never mutating h. A header error
+// still aborts the whole pass, since no row can be meaningfully checked
+// without it.
+func (h *HoldingDB) Validate(r io.Reader) []error {
+	source := csv.NewReader(r)
+	source.FieldsPerRecord = -1
+
+	hasLotIDColumns, err := readCSVHeader(source)
+	if err != nil {
+		return []error{err}
+	}
+
+	scratch := h.clone()
+	var errs []error
+	rowNum := 0
+	for {
+		row, err := source.Read()
+		if err != nil {
+			if err == io.EOF {
+				return errs
+			}
+			return append(errs, err)
+		}
+		rowNum++
+
+		events, err := parseCSVRow(rowNum, row, hasLotIDColumns)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := scratch.LoadEvents(events); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+func init() {
+	RegisterImporter("csv", CSVImporter{}, "csv")
+}
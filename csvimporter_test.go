@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const csvTestHeader = ",Buy,,,,,Trades,,,,,,,Transfers,,,,,,,Sales,,,,,,\n" +
+	",Amount,Currency,Unit basis,USD Value,,Amount,Source currency,Amount,Target currency,Unit price,Target amount after fees,,Amount,Currency,Source,Target,Fees (in addition to Amount),,,Amount,Currency,Unit price,Fees (in addition to Amount),USD Net,,URL\n"
+
+func csvRow(date, buyAmount, buyCurrency, buyBasis string) string {
+	return date + "," + buyAmount + "," + buyCurrency + "," + buyBasis + ",,,,,,,,,,,,,,,,,,,,,,,\n"
+}
+
+func TestValidateCollectsMultipleErrorsWithoutMutatingH(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "5", "100", "2023-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	csv := csvTestHeader +
+		csvRow("", "1", "BTC", "100") + // missing date
+		csvRow("2023-02-01", "1", "DOGE", "100") // unknown currency (strict, default)
+
+	errs := db.Validate(strings.NewReader(csv))
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+
+	// h itself must be untouched: still exactly the one BTC lot bought
+	// above, none of the scratch replay's rows applied.
+	if len(db.Holdings) != 1 {
+		t.Fatalf("Validate mutated h.Holdings: got %d holdings, want 1", len(db.Holdings))
+	}
+	if got := db.balanceFor(DefaultAccount, "BTC"); got.Cmp(mustRat(t, "5")) != 0 {
+		t.Fatalf("Validate mutated h's balance: got %s, want 5", got.FloatString(8))
+	}
+	if db.knownCurrencies["DOGE"] {
+		t.Fatal("Validate registered DOGE into h's known-currency set")
+	}
+}
+
+func TestValidateReportsNoErrorsForAValidCSV(t *testing.T) {
+	db := NewHoldingDB()
+	csv := csvTestHeader + csvRow("2023-01-01", "1", "BTC", "100")
+	if errs := db.Validate(strings.NewReader(csv)); len(errs) != 0 {
+		t.Fatalf("got errors for a valid CSV: %v", errs)
+	}
+}
+
+func TestValidateRejectsUnrecognizedHeader(t *testing.T) {
+	db := NewHoldingDB()
+	errs := db.Validate(strings.NewReader("not,the,right,header\n"))
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+// ErrUnknownCurrency is returned when a Buy/Sell/Trade/Transfer/Dividend
+// names a currency not in KnownCurrencies (see HoldingDB.Lenient).
+type ErrUnknownCurrency struct {
+	Currency string
+}
+
+func (e ErrUnknownCurrency) Error() string {
+	return fmt.Sprintf("unknown currency %q", e.Currency)
+}
+
+// ErrNegativeBalance is returned when a Sell/Transfer would draw an
+// account's balance of a currency below zero.
+type ErrNegativeBalance struct {
+	Account  string
+	Currency string
+}
+
+func (e ErrNegativeBalance) Error() string {
+	label := e.Account
+	if label == DefaultAccount {
+		label = "(default)"
+	}
+	return fmt.Sprintf("negative balance for %s in account %s", e.Currency, label)
+}
+
+// ErrMalformedRow is returned by the CSV importer and HoldingDB.Validate
+// for a row that can't be parsed. Row is 1-based among data rows (the two
+// header rows aren't counted); Column names the field or section at fault.
+type ErrMalformedRow struct {
+	Row    int
+	Column string
+	Reason string
+}
+
+func (e ErrMalformedRow) Error() string {
+	if e.Row == 0 {
+		return fmt.Sprintf("%s: %s", e.Column, e.Reason)
+	}
+	return fmt.Sprintf("row %d, column %q: %s", e.Row, e.Column, e.Reason)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"math/big"
+)
+
+// Form8949Reporter emits IRS Form 8949-shaped rows: a Part I (short-term)
+// section followed by a Part II (long-term) section, each with the
+// paper form's column layout.
+type Form8949Reporter struct{}
+
+var form8949Header = []string{
+	"Description", "Acquired", "Sold", "Proceeds", "Basis",
+	"Adjustment Code", "Adjustment", "Gain/Loss",
+}
+
+func (Form8949Reporter) Report(w io.Writer, db *HoldingDB, year int) error {
+	events, err := eventsForYear(db, year)
+	if err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	defer out.Flush()
+
+	if err := out.Write([]string{"Part I - Short-Term"}); err != nil {
+		return err
+	}
+	if err := out.Write(form8949Header); err != nil {
+		return err
+	}
+	if err := writeForm8949Rows(out, events, false); err != nil {
+		return err
+	}
+
+	if err := out.Write([]string{}); err != nil {
+		return err
+	}
+	if err := out.Write([]string{"Part II - Long-Term"}); err != nil {
+		return err
+	}
+	if err := out.Write(form8949Header); err != nil {
+		return err
+	}
+	return writeForm8949Rows(out, events, true)
+}
+
+func writeForm8949Rows(out *csv.Writer, events []*TaxEvent, longTerm bool) error {
+	for _, event := range events {
+		if event.LongTerm != longTerm {
+			continue
+		}
+
+		proceeds := new(big.Rat).Mul(event.Amount, event.SalePricePerUnitInUSD)
+		basis := new(big.Rat).Mul(event.Amount, event.AverageCostBasisPerUnitInUSD)
+		gainOrLoss := new(big.Rat).Sub(proceeds, basis)
+
+		adjCode, adjustment := "", "0.00"
+		if event.DisallowedLoss != nil {
+			adjCode = "W"
+			adjustment = event.DisallowedLoss.FloatString(2)
+			gainOrLoss.Add(gainOrLoss, event.DisallowedLoss)
+		}
+
+		row := []string{
+			format(event.Amount) + " " + event.Currency,
+			dateRange(setToStrings(event.AcquisitionDates)),
+			event.Date,
+			proceeds.FloatString(2),
+			basis.FloatString(2),
+			adjCode,
+			adjustment,
+			gainOrLoss.FloatString(2),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterReporter("8949", Form8949Reporter{})
+}
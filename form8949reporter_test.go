@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestForm8949ReporterSeparatesShortAndLongTermSections(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2020-01-01", "", ""); err != nil { // long-term by the time it's sold
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "100", "2023-06-01", "", ""); err != nil { // short-term
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "150", "2023-07-01", "", "BTC-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "80", "2023-07-02", "", "BTC-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (Form8949Reporter{}).Report(&buf, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	shortIdx := strings.Index(out, "Part I - Short-Term")
+	longIdx := strings.Index(out, "Part II - Long-Term")
+	if shortIdx == -1 || longIdx == -1 || shortIdx > longIdx {
+		t.Fatalf("expected Part I before Part II, got:\n%s", out)
+	}
+	if !strings.Contains(out, "150.00") {
+		t.Errorf("missing long-term proceeds in output:\n%s", out)
+	}
+	if !strings.Contains(out, "80.00") {
+		t.Errorf("missing short-term proceeds in output:\n%s", out)
+	}
+}
+
+func TestForm8949ReporterEmitsWashSaleAdjustmentColumn(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2023-01-01", "", "lot-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "90", "2023-01-20", "", "lot-b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "50", "2023-01-25", "", "lot-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (Form8949Reporter{}).Report(&buf, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, ",W,") {
+		t.Fatalf("expected a W adjustment code in output:\n%s", out)
+	}
+	// basis must stay at the actually-paid 100.00, not be inflated by the
+	// disallowed loss (see TXFReporter's equivalent, which the Form 8949
+	// columns were the model for).
+	if !strings.Contains(out, "100.00") {
+		t.Errorf("expected the unadjusted basis 100.00 in output:\n%s", out)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// GeminiImporter reads Gemini's simplified "Transaction History" CSV
+// export (Date, Type, Symbol, Amount, Price, Fee columns). Gemini's full
+// per-pair statement export uses asset-specific column names (e.g. "BTC
+// Amount BTC") and isn't handled here.
+type GeminiImporter struct{}
+
+func (GeminiImporter) Import(r io.Reader) ([]Event, error) {
+	source := csv.NewReader(r)
+	source.FieldsPerRecord = -1
+
+	header, err := source.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := indexHeader(header)
+	for _, name := range []string{"date", "type", "symbol", "amount", "price"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("gemini csv: missing %q column", name)
+		}
+	}
+
+	var events []Event
+	for {
+		row, err := source.Read()
+		if err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return nil, err
+		}
+
+		date := row[col["date"]]
+		txType := row[col["type"]]
+		currency := row[col["symbol"]]
+		amount := row[col["amount"]]
+		price := row[col["price"]]
+
+		switch txType {
+		case "Buy":
+			events = append(events, Event{
+				Kind: EventBuy, Date: date, Currency: currency,
+				Amount: amount, UnitPrice: price, Tags: "gemini",
+			})
+		case "Sell":
+			events = append(events, Event{
+				Kind: EventSell, Date: date, Currency: currency,
+				Amount: amount, UnitPrice: price, Tags: "gemini",
+			})
+		case "Interest Credit", "Earn Credit":
+			events = append(events, Event{
+				Kind: EventDividend, Date: date, Currency: currency,
+				Amount: amount, Tags: "gemini-income",
+			})
+		}
+	}
+}
+
+func init() {
+	RegisterImporter("gemini", GeminiImporter{})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeminiImporterMapsTransactionTypes(t *testing.T) {
+	csv := "date,type,symbol,amount,price\n" +
+		"2023-01-01,Buy,BTC,1.5,20000\n" +
+		"2023-02-01,Sell,BTC,0.5,25000\n" +
+		"2023-03-01,Interest Credit,BTC,0.01,\n"
+
+	events, err := (GeminiImporter{}).Import(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	if buy := events[0]; buy.Kind != EventBuy || buy.Amount != "1.5" || buy.UnitPrice != "20000" {
+		t.Errorf("unexpected buy event: %+v", buy)
+	}
+	if sell := events[1]; sell.Kind != EventSell || sell.Amount != "0.5" {
+		t.Errorf("unexpected sell event: %+v", sell)
+	}
+	if income := events[2]; income.Kind != EventDividend || income.Amount != "0.01" {
+		t.Errorf("unexpected income event: %+v", income)
+	}
+}
+
+func TestGeminiImporterErrorsOnMissingColumn(t *testing.T) {
+	csv := "date,type,amount,price\n2023-01-01,Buy,1,100\n"
+	if _, err := (GeminiImporter{}).Import(strings.NewReader(csv)); err == nil {
+		t.Fatal("expected an error for a missing symbol column, got nil")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// EventKind identifies what a normalized Event represents.
+type EventKind int
+
+const (
+	EventBuy EventKind = iota
+	EventSell
+	EventTrade
+	EventTransfer
+	EventDividend
+)
+
+// Event is the normalized ledger entry every Importer produces, regardless
+// of the statement format it came from. HoldingDB.LoadEvents turns a
+// stream of these into Buy/Sell/Trade calls.
+type Event struct {
+	Kind EventKind
+	Date string
+	Tags string
+
+	// Buy / Sell
+	Currency  string
+	Amount    string
+	UnitPrice string   // cost basis (Buy) or sale price (Sell), in USD
+	LotID     string   // Buy: assign this lot ID instead of auto-generating one
+	LotIDs    []string // Sell: draw only from these specific lot IDs
+
+	// Trade: exactly one of UnitPrice/TargetUnitPrice should be set, same
+	// as HoldingDB.Trade's source/target price arguments.
+	TargetCurrency  string
+	TargetAmount    string
+	TargetUnitPrice string
+
+	// Transfer
+	Source string
+	Target string
+	Fee    string
+}
+
+// Importer turns a raw statement or export into a normalized Event stream.
+type Importer interface {
+	Import(r io.Reader) ([]Event, error)
+}
+
+type importerRegistration struct {
+	name string
+	exts []string
+	imp  Importer
+}
+
+var importerRegistry []importerRegistration
+
+// RegisterImporter makes imp selectable by name (for --format) and, for any
+// extensions given, by file suffix.
+func RegisterImporter(name string, imp Importer, exts ...string) {
+	importerRegistry = append(importerRegistry, importerRegistration{name, exts, imp})
+}
+
+// ImporterByName looks up an Importer registered under name.
+func ImporterByName(name string) (Importer, bool) {
+	for _, reg := range importerRegistry {
+		if reg.name == name {
+			return reg.imp, true
+		}
+	}
+	return nil, false
+}
+
+// ImporterForFile picks an Importer by path's extension.
+func ImporterForFile(path string) (Importer, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	for _, reg := range importerRegistry {
+		for _, candidate := range reg.exts {
+			if candidate == ext {
+				return reg.imp, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// LoadEvents replays a normalized Event stream against the DB.
+func (h *HoldingDB) LoadEvents(events []Event) error {
+	for _, event := range events {
+		var err error
+		switch event.Kind {
+		case EventBuy:
+			_, err = h.Buy(event.Currency, event.Amount, event.UnitPrice, event.Date, event.Tags, event.LotID)
+		case EventSell:
+			err = h.Sell(event.Currency, event.Amount, event.UnitPrice, event.Date, event.Tags, event.LotIDs...)
+		case EventTrade:
+			err = h.Trade(event.Currency, event.TargetCurrency, event.Amount, event.TargetAmount,
+				event.UnitPrice, event.TargetUnitPrice, event.Date)
+		case EventTransfer:
+			err = h.Transfer(event.Currency, event.Amount, event.Source, event.Target, event.Fee, event.Date)
+		case EventDividend:
+			err = h.Dividend(event.Currency, event.Amount, event.Date, event.Tags)
+		default:
+			err = fmt.Errorf("unknown event kind %d", event.Kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
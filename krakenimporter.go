@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KrakenImporter reads a Kraken "ledgers" CSV export. Kraken records each
+// side of a trade as a separate row sharing a refid (one debiting the
+// source asset, one crediting the target); those pairs are merged into a
+// single Trade event. USD prices aren't present in the ledger, so the
+// resulting Trade event carries no price and relies on a PriceOracle to
+// fill one in.
+type KrakenImporter struct{}
+
+func (KrakenImporter) Import(r io.Reader) ([]Event, error) {
+	source := csv.NewReader(r)
+	source.FieldsPerRecord = -1
+
+	header, err := source.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := indexHeader(header)
+	for _, name := range []string{"refid", "time", "type", "asset", "amount"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("kraken csv: missing %q column", name)
+		}
+	}
+
+	byRef := map[string][]map[string]string{}
+	var refOrder []string
+	for {
+		row, err := source.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		rec := map[string]string{}
+		for name, idx := range col {
+			if idx < len(row) {
+				rec[name] = row[idx]
+			}
+		}
+		if rec["type"] != "trade" {
+			continue
+		}
+		ref := rec["refid"]
+		if _, ok := byRef[ref]; !ok {
+			refOrder = append(refOrder, ref)
+		}
+		byRef[ref] = append(byRef[ref], rec)
+	}
+
+	var events []Event
+	for _, ref := range refOrder {
+		legs := byRef[ref]
+		if len(legs) != 2 {
+			continue // can't pair it into a trade; skip rather than guess
+		}
+		sourceLeg, targetLeg := legs[0], legs[1]
+		if !strings.HasPrefix(sourceLeg["amount"], "-") {
+			sourceLeg, targetLeg = targetLeg, sourceLeg
+		}
+		events = append(events, Event{
+			Kind:           EventTrade,
+			Date:           dateOnly(sourceLeg["time"]),
+			Currency:       sourceLeg["asset"],
+			Amount:         strings.TrimPrefix(sourceLeg["amount"], "-"),
+			TargetCurrency: targetLeg["asset"],
+			TargetAmount:   targetLeg["amount"],
+			Tags:           "kraken",
+		})
+	}
+	return events, nil
+}
+
+func indexHeader(header []string) map[string]int {
+	idx := make(map[string]int, len(header))
+	for i, name := range header {
+		idx[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return idx
+}
+
+func init() {
+	RegisterImporter("kraken", KrakenImporter{})
+}
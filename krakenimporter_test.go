@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKrakenImporterPairsLegsIntoATrade(t *testing.T) {
+	csv := "refid,time,type,asset,amount\n" +
+		"ref1,2023-01-01T00:00:00Z,trade,BTC,-1\n" +
+		"ref1,2023-01-01T00:00:00Z,trade,ETH,15\n" +
+		"ref2,2023-02-01T00:00:00Z,deposit,USD,100\n"
+
+	events, err := (KrakenImporter{}).Import(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1 (the deposit row should be skipped): %+v", len(events), events)
+	}
+
+	trade := events[0]
+	if trade.Kind != EventTrade || trade.Date != "2023-01-01" {
+		t.Fatalf("unexpected trade event: %+v", trade)
+	}
+	if trade.Currency != "BTC" || trade.Amount != "1" {
+		t.Errorf("source leg: got currency %q amount %q, want BTC 1", trade.Currency, trade.Amount)
+	}
+	if trade.TargetCurrency != "ETH" || trade.TargetAmount != "15" {
+		t.Errorf("target leg: got currency %q amount %q, want ETH 15", trade.TargetCurrency, trade.TargetAmount)
+	}
+}
+
+func TestKrakenImporterSkipsUnpairedLegs(t *testing.T) {
+	csv := "refid,time,type,asset,amount\n" +
+		"ref1,2023-01-01T00:00:00Z,trade,BTC,-1\n"
+
+	events, err := (KrakenImporter{}).Import(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("got %d events for a lone unpaired leg, want 0: %+v", len(events), events)
+	}
+}
+
+func TestKrakenImporterErrorsOnMissingColumn(t *testing.T) {
+	csv := "time,type,asset,amount\n2023-01-01,trade,BTC,1\n"
+	if _, err := (KrakenImporter{}).Import(strings.NewReader(csv)); err == nil {
+		t.Fatal("expected an error for a missing refid column, got nil")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"math/big"
+)
+
+// LotConsumption records that Amount units of Holding were pulled out of a
+// lot to satisfy a sale. Amount may be less than Holding.Amount, in which
+// case the remainder of the lot is left untouched.
+type LotConsumption struct {
+	Holding *Holding
+	Amount  *big.Rat
+}
+
+// LotSelector decides which holdings (and how much of each) a sale of
+// amount units of currency should draw from, and in what order. Selectors
+// only see holdings already filtered down to the matching currency.
+type LotSelector interface {
+	Select(currency string, amount *big.Rat, holdings []*Holding) ([]LotConsumption, error)
+}
+
+// consumeInOrder walks ordered and greedily consumes whole or partial lots
+// until amount is satisfied. It is the shared core of every selector below;
+// they differ only in how they order the candidate holdings.
+func consumeInOrder(currency string, amount *big.Rat, ordered []*Holding) ([]LotConsumption, error) {
+	remaining := new(big.Rat).Set(amount)
+	var consumptions []LotConsumption
+	for _, holding := range ordered {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		if holding.Currency != currency || holding.Amount.Sign() <= 0 {
+			continue
+		}
+		take := new(big.Rat).Set(holding.Amount)
+		if less(remaining, holding.Amount) {
+			take.Set(remaining)
+		}
+		consumptions = append(consumptions, LotConsumption{Holding: holding, Amount: take})
+		remaining.Sub(remaining, take)
+	}
+	if remaining.Sign() > 0 {
+		return nil, fmt.Errorf("insufficient %s holdings to sell %s (short by %s)",
+			currency, amount.FloatString(8), remaining.FloatString(8))
+	}
+	return consumptions, nil
+}
+
+// FIFOSelector consumes the oldest lots first.
+type FIFOSelector struct{}
+
+func (FIFOSelector) Select(currency string, amount *big.Rat, holdings []*Holding) ([]LotConsumption, error) {
+	ordered := append([]*Holding{}, holdings...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].AcquisitionDate.Before(ordered[j].AcquisitionDate)
+	})
+	return consumeInOrder(currency, amount, ordered)
+}
+
+// LIFOSelector consumes the newest lots first.
+type LIFOSelector struct{}
+
+func (LIFOSelector) Select(currency string, amount *big.Rat, holdings []*Holding) ([]LotConsumption, error) {
+	ordered := append([]*Holding{}, holdings...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[j].AcquisitionDate.Before(ordered[i].AcquisitionDate)
+	})
+	return consumeInOrder(currency, amount, ordered)
+}
+
+// HIFOSelector consumes the highest-cost-basis lots first, minimizing the
+// realized gain (or maximizing the realized loss). This is the classic
+// crypto tax-optimization strategy.
+type HIFOSelector struct{}
+
+func (HIFOSelector) Select(currency string, amount *big.Rat, holdings []*Holding) ([]LotConsumption, error) {
+	ordered := append([]*Holding{}, holdings...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return less(ordered[j].CostBasisPerUnitInUSD, ordered[i].CostBasisPerUnitInUSD)
+	})
+	return consumeInOrder(currency, amount, ordered)
+}
+
+// LOFOSelector consumes the lowest-cost-basis lots first, maximizing the
+// realized gain.
+type LOFOSelector struct{}
+
+func (LOFOSelector) Select(currency string, amount *big.Rat, holdings []*Holding) ([]LotConsumption, error) {
+	ordered := append([]*Holding{}, holdings...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return less(ordered[i].CostBasisPerUnitInUSD, ordered[j].CostBasisPerUnitInUSD)
+	})
+	return consumeInOrder(currency, amount, ordered)
+}
+
+// SpecificIDSelector implements Specific Identification: the caller names
+// exactly which lots (by Holding.ID) to draw from, and in what order.
+type SpecificIDSelector struct {
+	IDs []string
+}
+
+func (s SpecificIDSelector) Select(currency string, amount *big.Rat, holdings []*Holding) ([]LotConsumption, error) {
+	byID := make(map[string]*Holding, len(holdings))
+	for _, holding := range holdings {
+		byID[holding.ID] = holding
+	}
+	ordered := make([]*Holding, 0, len(s.IDs))
+	for _, id := range s.IDs {
+		holding, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("specific-id lot selector: no %s holding with id %q", currency, id)
+		}
+		ordered = append(ordered, holding)
+	}
+	return consumeInOrder(currency, amount, ordered)
+}
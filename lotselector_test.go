@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func mustRat(t *testing.T, s string) *big.Rat {
+	t.Helper()
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		t.Fatalf("bad rat literal %q", s)
+	}
+	return r
+}
+
+func testHolding(t *testing.T, id, amount, costBasis, acquired string) *Holding {
+	t.Helper()
+	date, err := time.Parse("2006-01-02", acquired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Holding{
+		ID:                    id,
+		Currency:              "BTC",
+		Amount:                mustRat(t, amount),
+		CostBasisPerUnitInUSD: mustRat(t, costBasis),
+		AcquisitionDate:       date,
+	}
+}
+
+func assertConsumptions(t *testing.T, got []LotConsumption, wantIDs []string, wantAmounts []string) {
+	t.Helper()
+	if len(got) != len(wantIDs) {
+		t.Fatalf("got %d consumptions, want %d: %+v", len(got), len(wantIDs), got)
+	}
+	for i, c := range got {
+		if c.Holding.ID != wantIDs[i] {
+			t.Errorf("consumption %d: got lot %q, want %q", i, c.Holding.ID, wantIDs[i])
+		}
+		if c.Amount.Cmp(mustRat(t, wantAmounts[i])) != 0 {
+			t.Errorf("consumption %d (lot %s): got amount %s, want %s", i, c.Holding.ID, c.Amount.FloatString(8), wantAmounts[i])
+		}
+	}
+}
+
+func TestFIFOSelectorConsumesOldestLotsFirst(t *testing.T) {
+	holdings := []*Holding{
+		testHolding(t, "late", "1", "200", "2021-06-01"),
+		testHolding(t, "early", "1", "100", "2021-01-01"),
+	}
+	got, err := FIFOSelector{}.Select("BTC", mustRat(t, "1.5"), holdings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertConsumptions(t, got, []string{"early", "late"}, []string{"1", "0.5"})
+}
+
+func TestLIFOSelectorConsumesNewestLotsFirst(t *testing.T) {
+	holdings := []*Holding{
+		testHolding(t, "early", "1", "100", "2021-01-01"),
+		testHolding(t, "late", "1", "200", "2021-06-01"),
+	}
+	got, err := LIFOSelector{}.Select("BTC", mustRat(t, "1.5"), holdings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertConsumptions(t, got, []string{"late", "early"}, []string{"1", "0.5"})
+}
+
+func TestHIFOSelectorConsumesHighestCostBasisFirst(t *testing.T) {
+	holdings := []*Holding{
+		testHolding(t, "cheap", "1", "100", "2021-01-01"),
+		testHolding(t, "pricey", "1", "500", "2021-06-01"),
+	}
+	got, err := HIFOSelector{}.Select("BTC", mustRat(t, "1.5"), holdings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertConsumptions(t, got, []string{"pricey", "cheap"}, []string{"1", "0.5"})
+}
+
+func TestLOFOSelectorConsumesLowestCostBasisFirst(t *testing.T) {
+	holdings := []*Holding{
+		testHolding(t, "pricey", "1", "500", "2021-06-01"),
+		testHolding(t, "cheap", "1", "100", "2021-01-01"),
+	}
+	got, err := LOFOSelector{}.Select("BTC", mustRat(t, "1.5"), holdings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertConsumptions(t, got, []string{"cheap", "pricey"}, []string{"1", "0.5"})
+}
+
+func TestSpecificIDSelectorPullsNamedLotsInGivenOrder(t *testing.T) {
+	holdings := []*Holding{
+		testHolding(t, "a", "1", "100", "2021-01-01"),
+		testHolding(t, "b", "1", "200", "2021-02-01"),
+		testHolding(t, "c", "1", "300", "2021-03-01"),
+	}
+	got, err := SpecificIDSelector{IDs: []string{"c", "a"}}.Select("BTC", mustRat(t, "1.5"), holdings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertConsumptions(t, got, []string{"c", "a"}, []string{"1", "0.5"})
+}
+
+func TestSpecificIDSelectorUnknownIDErrors(t *testing.T) {
+	holdings := []*Holding{testHolding(t, "a", "1", "100", "2021-01-01")}
+	selector := SpecificIDSelector{IDs: []string{"missing"}}
+	if _, err := selector.Select("BTC", mustRat(t, "1"), holdings); err == nil {
+		t.Fatal("expected an error for an unknown lot id, got nil")
+	}
+}
+
+func TestConsumeInOrderInsufficientHoldingsErrors(t *testing.T) {
+	holdings := []*Holding{testHolding(t, "a", "1", "100", "2021-01-01")}
+	selector := FIFOSelector{}
+	if _, err := selector.Select("BTC", mustRat(t, "2"), holdings); err == nil {
+		t.Fatal("expected an error when the sale exceeds available holdings, got nil")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// OFXImporter reads OFX/QFX investment statements (INVTRANLIST), mapping
+// BUYSTOCK/REINVEST to Buy, SELLSTOCK to Sell, and INCOME to a Dividend.
+// It assumes the SECID/UNIQUEID element carries the
+// currency ticker directly, which is how crypto-focused OFX exports do
+// it (brokerage exports instead use a CUSIP requiring a SECLIST lookup,
+// which isn't handled here).
+type OFXImporter struct{}
+
+var ofxTagRE = regexp.MustCompile(`<([A-Za-z0-9.]+)>([^<\r\n]*)`)
+
+func (OFXImporter) Import(r io.Reader) ([]Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var events []Event
+	blockKind := ""
+	fields := map[string]string{}
+
+	flush := func() error {
+		if blockKind == "" {
+			return nil
+		}
+		event, err := ofxEvent(blockKind, fields)
+		if err != nil {
+			return err
+		}
+		if event != nil {
+			events = append(events, *event)
+		}
+		blockKind = ""
+		fields = map[string]string{}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "<BUYSTOCK>", "<SELLSTOCK>", "<INCOME>", "<REINVEST>":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			blockKind = strings.Trim(line, "<>")
+			continue
+		case "</BUYSTOCK>", "</SELLSTOCK>", "</INCOME>", "</REINVEST>":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if blockKind == "" {
+			continue
+		}
+		if m := ofxTagRE.FindStringSubmatch(line); m != nil {
+			fields[m[1]] = m[2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func ofxEvent(kind string, fields map[string]string) (*Event, error) {
+	currency := fields["UNIQUEID"]
+	date := ofxDate(fields["DTTRADE"])
+	if date == "" {
+		date = ofxDate(fields["DTPOSTED"])
+	}
+	if currency == "" || date == "" {
+		return nil, fmt.Errorf("ofx %s block missing SECID/date", kind)
+	}
+
+	switch kind {
+	case "BUYSTOCK", "REINVEST":
+		return &Event{
+			Kind:      EventBuy,
+			Date:      date,
+			Currency:  currency,
+			Amount:    fields["UNITS"],
+			UnitPrice: fields["UNITPRICE"],
+			Tags:      strings.ToLower(kind),
+		}, nil
+	case "SELLSTOCK":
+		return &Event{
+			Kind:      EventSell,
+			Date:      date,
+			Currency:  currency,
+			Amount:    strings.TrimPrefix(fields["UNITS"], "-"),
+			UnitPrice: fields["UNITPRICE"],
+			Tags:      "sellstock",
+		}, nil
+	case "INCOME":
+		return &Event{
+			Kind:     EventDividend,
+			Date:     date,
+			Currency: currency,
+			Amount:   fields["TOTAL"],
+			Tags:     "income",
+		}, nil
+	}
+	return nil, nil
+}
+
+func ofxDate(raw string) string {
+	if len(raw) < 8 {
+		return ""
+	}
+	return raw[0:4] + "-" + raw[4:6] + "-" + raw[6:8]
+}
+
+func init() {
+	RegisterImporter("ofx", OFXImporter{}, "ofx", "qfx")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOFXImporterMapsTransactionTypes(t *testing.T) {
+	ofx := `
+<INVTRANLIST>
+<BUYSTOCK>
+<INVBUY>
+<INVTRAN>
+<DTTRADE>20230101
+</INVTRAN>
+<SECID>
+<UNIQUEID>BTC
+</SECID>
+<UNITS>1.5
+<UNITPRICE>20000
+</INVBUY>
+</BUYSTOCK>
+<SELLSTOCK>
+<INVSELL>
+<INVTRAN>
+<DTTRADE>20230201
+</INVTRAN>
+<SECID>
+<UNIQUEID>BTC
+</SECID>
+<UNITS>-0.5
+<UNITPRICE>25000
+</INVSELL>
+</SELLSTOCK>
+<INCOME>
+<INVTRAN>
+<DTPOSTED>20230301
+</INVTRAN>
+<SECID>
+<UNIQUEID>BTC
+</SECID>
+<TOTAL>0.01
+</INCOME>
+</INVTRANLIST>
+`
+	events, err := (OFXImporter{}).Import(strings.NewReader(ofx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	buy := events[0]
+	if buy.Kind != EventBuy || buy.Date != "2023-01-01" || buy.Currency != "BTC" || buy.Amount != "1.5" || buy.UnitPrice != "20000" {
+		t.Errorf("unexpected buy event: %+v", buy)
+	}
+
+	sell := events[1]
+	if sell.Kind != EventSell || sell.Date != "2023-02-01" || sell.Amount != "0.5" || sell.UnitPrice != "25000" {
+		t.Errorf("unexpected sell event (UNITS should have its leading '-' stripped): %+v", sell)
+	}
+
+	income := events[2]
+	if income.Kind != EventDividend || income.Date != "2023-03-01" || income.Amount != "0.01" {
+		t.Errorf("unexpected income event: %+v", income)
+	}
+}
+
+func TestOFXImporterErrorsOnMissingSecIDOrDate(t *testing.T) {
+	ofx := `
+<INVTRANLIST>
+<BUYSTOCK>
+<INVBUY>
+<INVTRAN>
+<DTTRADE>20230101
+</INVTRAN>
+<UNITS>1
+<UNITPRICE>100
+</INVBUY>
+</BUYSTOCK>
+</INVTRANLIST>
+`
+	if _, err := (OFXImporter{}).Import(strings.NewReader(ofx)); err == nil {
+		t.Fatal("expected an error for a block missing SECID, got nil")
+	}
+}
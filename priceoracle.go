@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// PriceOracle resolves a spot USD price for a (currency, date) pair. Buy,
+// Sell, and Trade fall back to it whenever a row omits an explicit USD
+// price.
+type PriceOracle interface {
+	PriceInUSD(currency string, date time.Time) (*big.Rat, error)
+}
+
+// PriceFetcher looks up a price CachedPriceOracle doesn't already have
+// cached, e.g. by calling out to an HTTP price API.
+type PriceFetcher interface {
+	FetchPriceInUSD(currency string, date time.Time) (*big.Rat, error)
+}
+
+// CachedPriceOracle serves prices loaded from disk (LoadCSV/LoadJSON) and,
+// on a miss, consults an optional Fetcher and remembers the result.
+type CachedPriceOracle struct {
+	prices  map[string]map[string]*big.Rat // currency -> "2006-01-02" -> price
+	Fetcher PriceFetcher
+}
+
+func NewCachedPriceOracle() *CachedPriceOracle {
+	return &CachedPriceOracle{prices: map[string]map[string]*big.Rat{}}
+}
+
+func (o *CachedPriceOracle) PriceInUSD(currency string, date time.Time) (*big.Rat, error) {
+	key := date.Format("2006-01-02")
+	if byDate, ok := o.prices[currency]; ok {
+		if price, ok := byDate[key]; ok {
+			return price, nil
+		}
+	}
+	if o.Fetcher == nil {
+		return nil, fmt.Errorf("no cached price for %s on %s", currency, key)
+	}
+	price, err := o.Fetcher.FetchPriceInUSD(currency, date)
+	if err != nil {
+		return nil, err
+	}
+	o.Set(currency, date, price)
+	return price, nil
+}
+
+// Set records (or overrides) the cached price for currency on date.
+func (o *CachedPriceOracle) Set(currency string, date time.Time, price *big.Rat) {
+	if o.prices[currency] == nil {
+		o.prices[currency] = map[string]*big.Rat{}
+	}
+	o.prices[currency][date.Format("2006-01-02")] = price
+}
+
+// LoadCSV populates the cache from rows of currency,date,priceUSD.
+func (o *CachedPriceOracle) LoadCSV(r io.Reader) error {
+	source := csv.NewReader(r)
+	for {
+		row, err := source.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(row) < 3 {
+			continue
+		}
+		date, err := parseTime(row[1])
+		if err != nil {
+			return err
+		}
+		price, ok := new(big.Rat).SetString(row[2])
+		if !ok {
+			return fmt.Errorf("invalid price %q for %s on %s", row[2], row[0], row[1])
+		}
+		o.Set(row[0], date, price)
+	}
+}
+
+// LoadJSON populates the cache from {"BTC": {"2023-01-01": "20000.00"}}.
+func (o *CachedPriceOracle) LoadJSON(r io.Reader) error {
+	var raw map[string]map[string]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+	for currency, byDate := range raw {
+		for dateStr, priceStr := range byDate {
+			date, err := parseTime(dateStr)
+			if err != nil {
+				return err
+			}
+			price, ok := new(big.Rat).SetString(priceStr)
+			if !ok {
+				return fmt.Errorf("invalid price %q for %s on %s", priceStr, currency, dateStr)
+			}
+			o.Set(currency, date, price)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import "io"
+
+// Reporter renders a HoldingDB's realized tax events for year (0 means
+// every year) to w, in some downstream-consumable format.
+type Reporter interface {
+	Report(w io.Writer, db *HoldingDB, year int) error
+}
+
+var reporterRegistry = map[string]Reporter{}
+
+// RegisterReporter makes a Reporter selectable by name via --report.
+func RegisterReporter(name string, r Reporter) {
+	reporterRegistry[name] = r
+}
+
+func ReporterByName(name string) (Reporter, bool) {
+	r, ok := reporterRegistry[name]
+	return r, ok
+}
+
+// eventsForYear returns db's TaxEvents in date order, restricted to year
+// (0 means no restriction).
+func eventsForYear(db *HoldingDB, year int) ([]*TaxEvent, error) {
+	var events []*TaxEvent
+	for _, date := range sortedEvents(db.TaxEvents, false) {
+		for _, event := range db.TaxEvents[date] {
+			if year != 0 {
+				eventDate, err := parseTime(event.Date)
+				if err != nil {
+					return nil, err
+				}
+				if eventDate.Year() != year {
+					continue
+				}
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// incomeForYear returns db's OrdinaryIncome in date order, restricted to
+// year (0 means no restriction).
+func incomeForYear(db *HoldingDB, year int) ([]*OrdinaryIncomeEvent, error) {
+	var income []*OrdinaryIncomeEvent
+	for _, date := range sortedIncomeDates(db.OrdinaryIncome, false) {
+		for _, entry := range db.OrdinaryIncome[date] {
+			if year != 0 {
+				entryDate, err := parseTime(entry.Date)
+				if err != nil {
+					return nil, err
+				}
+				if entryDate.Year() != year {
+					continue
+				}
+			}
+			income = append(income, entry)
+		}
+	}
+	return income, nil
+}
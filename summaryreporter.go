@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// SummaryReporter prints aggregate totals for a tax year: short-term and
+// long-term capital gain/loss, ordinary income, and disallowed
+// wash-sale losses.
+type SummaryReporter struct{}
+
+func (SummaryReporter) Report(w io.Writer, db *HoldingDB, year int) error {
+	events, err := eventsForYear(db, year)
+	if err != nil {
+		return err
+	}
+	income, err := incomeForYear(db, year)
+	if err != nil {
+		return err
+	}
+
+	var shortTermGain, longTermGain, disallowed big.Rat
+	for _, event := range events {
+		proceeds := new(big.Rat).Mul(event.Amount, event.SalePricePerUnitInUSD)
+		basis := new(big.Rat).Mul(event.Amount, event.AverageCostBasisPerUnitInUSD)
+		gainOrLoss := new(big.Rat).Sub(proceeds, basis)
+		if event.DisallowedLoss != nil {
+			disallowed.Add(&disallowed, event.DisallowedLoss)
+			gainOrLoss.Add(gainOrLoss, event.DisallowedLoss)
+		}
+		if event.LongTerm {
+			longTermGain.Add(&longTermGain, gainOrLoss)
+		} else {
+			shortTermGain.Add(&shortTermGain, gainOrLoss)
+		}
+	}
+
+	var ordinaryIncome big.Rat
+	for _, entry := range income {
+		ordinaryIncome.Add(&ordinaryIncome, entry.ValueInUSD)
+	}
+
+	label := "all years"
+	if year != 0 {
+		label = fmt.Sprintf("%d", year)
+	}
+	fmt.Fprintf(w, "Tax summary for %s\n", label)
+	fmt.Fprintf(w, "  Short-term capital gain/loss: $%s\n", shortTermGain.FloatString(2))
+	fmt.Fprintf(w, "  Long-term capital gain/loss:  $%s\n", longTermGain.FloatString(2))
+	fmt.Fprintf(w, "  Ordinary income:              $%s\n", ordinaryIncome.FloatString(2))
+	fmt.Fprintf(w, "  Disallowed wash-sale losses:  $%s\n", disallowed.FloatString(2))
+	return nil
+}
+
+func init() {
+	RegisterReporter("summary", SummaryReporter{})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSummaryReporterTotalsGainsIncomeAndDisallowedLosses(t *testing.T) {
+	db := NewHoldingDB()
+	oracle := NewCachedPriceOracle()
+	dividendDate, err := parseTime("2023-08-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oracle.Set("BTC", dividendDate, mustRat(t, "30000"))
+	db.Oracle = oracle
+
+	if _, err := db.Buy("BTC", "1", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "150", "2023-01-01", ""); err != nil { // long-term gain
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("ETH", "1", "100", "2023-06-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("ETH", "1", "80", "2023-07-01", ""); err != nil { // short-term loss
+		t.Fatal(err)
+	}
+	if err := db.Dividend("BTC", "0.1", "2023-08-01", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (SummaryReporter{}).Report(&buf, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "Long-term capital gain/loss:  $50.00") {
+		t.Errorf("unexpected long-term total in output:\n%s", out)
+	}
+	if !strings.Contains(out, "Short-term capital gain/loss: $-20.00") {
+		t.Errorf("unexpected short-term total in output:\n%s", out)
+	}
+	if !strings.Contains(out, "Disallowed wash-sale losses:  $0.00") {
+		t.Errorf("expected no disallowed wash-sale losses in output:\n%s", out)
+	}
+}
+
+func TestSummaryReporterFiltersByYear(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2021-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "200", "2022-01-01", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "100", "2022-06-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "150", "2023-06-01", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (SummaryReporter{}).Report(&buf, db, 2022); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Tax summary for 2022") {
+		t.Fatalf("expected the 2022 label in output:\n%s", out)
+	}
+	if !strings.Contains(out, "$100.00") {
+		t.Errorf("expected only the 2022 sale's $100 gain, got:\n%s", out)
+	}
+}
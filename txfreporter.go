@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// TXFReporter emits TurboTax/H&R Block's TXF interchange format, using
+// the N711 (short-term) / N713 (long-term) "stocks, bonds, mutual funds"
+// record types most tax software targets for Schedule D import.
+type TXFReporter struct{}
+
+func (TXFReporter) Report(w io.Writer, db *HoldingDB, year int) error {
+	events, err := eventsForYear(db, year)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "V042")
+	fmt.Fprintln(w, "Acrypto8949")
+	fmt.Fprintln(w, "^")
+
+	for _, event := range events {
+		ref := "N711"
+		if event.LongTerm {
+			ref = "N713"
+		}
+
+		proceeds := new(big.Rat).Mul(event.Amount, event.SalePricePerUnitInUSD)
+		basis := new(big.Rat).Mul(event.Amount, event.AverageCostBasisPerUnitInUSD)
+		gainOrLoss := new(big.Rat).Sub(proceeds, basis)
+		// The N711/N713 record has no field for an adjustment code or
+		// amount (unlike Form8949Reporter's Form 8949 columns), so a wash
+		// sale can only be reflected by adjusting the reported gain/loss
+		// and leaving basis and proceeds as actually realized.
+		if event.DisallowedLoss != nil {
+			gainOrLoss.Add(gainOrLoss, event.DisallowedLoss)
+		}
+
+		fmt.Fprintln(w, "TD")
+		fmt.Fprintln(w, ref)
+		fmt.Fprintln(w, "C1")
+		fmt.Fprintln(w, "L1")
+		fmt.Fprintf(w, "P%s %s\n", format(event.Amount), event.Currency)
+		fmt.Fprintf(w, "D%s\n", dateRange(setToStrings(event.AcquisitionDates)))
+		fmt.Fprintf(w, "D%s\n", event.Date)
+		fmt.Fprintf(w, "$%s\n", basis.FloatString(2))
+		fmt.Fprintf(w, "$%s\n", proceeds.FloatString(2))
+		fmt.Fprintf(w, "$%s\n", gainOrLoss.FloatString(2))
+		fmt.Fprintln(w, "^")
+	}
+	return nil
+}
+
+func init() {
+	RegisterReporter("txf", TXFReporter{})
+}
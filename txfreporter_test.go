@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTXFReporterEmitsShortAndLongTermRecordTypes(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2020-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "100", "2023-06-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "150", "2023-07-01", "", "BTC-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "80", "2023-07-02", "", "BTC-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (TXFReporter{}).Report(&buf, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "V042\n") {
+		t.Fatalf("expected a V042 header, got:\n%s", out)
+	}
+	if !strings.Contains(out, "N711") {
+		t.Errorf("missing N711 (short-term) record in output:\n%s", out)
+	}
+	if !strings.Contains(out, "N713") {
+		t.Errorf("missing N713 (long-term) record in output:\n%s", out)
+	}
+}
+
+func TestTXFReporterLeavesBasisUnadjustedForWashSale(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2023-01-01", "", "lot-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "90", "2023-01-20", "", "lot-b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "50", "2023-01-25", "", "lot-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	event := db.TaxEvents["2023-01-25"][0]
+	if event.DisallowedLoss == nil {
+		t.Fatal("expected the sale to trigger a wash sale")
+	}
+
+	var buf bytes.Buffer
+	if err := (TXFReporter{}).Report(&buf, db, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// basis (100.00) and proceeds (50.00) must be the actually-realized
+	// figures, not basis inflated by the disallowed loss, even though the
+	// reported gain/loss (0.00) is zeroed out by it.
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	l1 := -1
+	for i, line := range lines {
+		if line == "L1" {
+			l1 = i
+		}
+	}
+	if l1 == -1 || l1+6 >= len(lines) {
+		t.Fatalf("couldn't find the L1-prefixed TD record in output:\n%s", out)
+	}
+	basisLine, proceedsLine, gainLine := lines[l1+4], lines[l1+5], lines[l1+6]
+	if basisLine != "$100.00" {
+		t.Errorf("got basis line %q, want $100.00", basisLine)
+	}
+	if proceedsLine != "$50.00" {
+		t.Errorf("got proceeds line %q, want $50.00", proceedsLine)
+	}
+	if gainLine != "$0.00" {
+		t.Errorf("got gain/loss line %q, want $0.00", gainLine)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/big"
+	"time"
+)
+
+// DefaultWashSaleWindow is the IRS §1091 window: a replacement purchase
+// made within 30 days before or after a loss sale disallows that loss.
+// Many crypto filers still model their trades against this rule even
+// though it technically applies to "securities", not property.
+const DefaultWashSaleWindow = 30 * 24 * time.Hour
+
+// pendingWashSaleLoss is a loss sale whose replacement lot, if any, hasn't
+// been bought yet. It is resolved (or expires unmatched) by a later Buy.
+type pendingWashSaleLoss struct {
+	event    *TaxEvent
+	currency string
+	saleDate time.Time
+	loss     *big.Rat
+}
+
+func (h *HoldingDB) washSaleWindow() time.Duration {
+	if h.WashSaleWindow == 0 {
+		return DefaultWashSaleWindow
+	}
+	return h.WashSaleWindow
+}
+
+// checkWashSaleOnSell looks for a replacement lot of event.Currency already
+// on hand (acquired within the wash-sale window before saleDate) that
+// should absorb event's loss. If none is found yet, the loss is parked so
+// a later Buy within the window can still trigger the wash sale. soldLotIDs
+// names the lot(s) this very sale drew from (including any leftover,
+// partially-consumed remainder still in h.Holdings): those can never count
+// as the sale's own replacement.
+func (h *HoldingDB) checkWashSaleOnSell(event *TaxEvent, saleDate time.Time, soldLotIDs map[string]bool) {
+	loss := new(big.Rat).Sub(
+		new(big.Rat).Mul(event.Amount, event.AverageCostBasisPerUnitInUSD),
+		new(big.Rat).Mul(event.Amount, event.SalePricePerUnitInUSD))
+	if loss.Sign() <= 0 {
+		return
+	}
+
+	for _, holding := range h.Holdings {
+		if holding.Currency != event.Currency {
+			continue
+		}
+		if soldLotIDs[holding.ID] {
+			continue
+		}
+		if holding.AcquisitionDate.After(saleDate) {
+			continue
+		}
+		if saleDate.Sub(holding.AcquisitionDate) > h.washSaleWindow() {
+			continue
+		}
+		h.disallowLoss(event, loss, holding)
+		return
+	}
+
+	h.pendingWashSales = append(h.pendingWashSales, &pendingWashSaleLoss{
+		event:    event,
+		currency: event.Currency,
+		saleDate: saleDate,
+		loss:     loss,
+	})
+}
+
+// checkWashSaleOnBuy matches a newly-acquired holding against any pending
+// loss sale of the same currency still inside its wash-sale window.
+func (h *HoldingDB) checkWashSaleOnBuy(holding *Holding) {
+	remaining := h.pendingWashSales[:0]
+	for _, pending := range h.pendingWashSales {
+		if pending.currency == holding.Currency &&
+			!holding.AcquisitionDate.Before(pending.saleDate) &&
+			holding.AcquisitionDate.Sub(pending.saleDate) <= h.washSaleWindow() {
+			h.disallowLoss(pending.event, pending.loss, holding)
+			continue
+		}
+		remaining = append(remaining, pending)
+	}
+	h.pendingWashSales = remaining
+}
+
+// disallowLoss records that event's loss is disallowed under §1091 and
+// rolls it into the replacement holding's cost basis.
+func (h *HoldingDB) disallowLoss(event *TaxEvent, loss *big.Rat, holding *Holding) {
+	event.DisallowedLoss = loss
+	event.ReplacementLotID = holding.ID
+
+	addedBasisPerUnit := new(big.Rat).Quo(loss, holding.Amount)
+	holding.CostBasisPerUnitInUSD.Add(holding.CostBasisPerUnitInUSD, addedBasisPerUnit)
+}
@@ -0,0 +1,153 @@
+package main
+
+import "testing"
+
+// TestWashSaleDisallowsLossAgainstReplacementAlreadyHeld covers
+// checkWashSaleOnSell's path: the replacement lot is already on the
+// books (bought before the loss sale) when the sale happens.
+func TestWashSaleDisallowsLossAgainstReplacementAlreadyHeld(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2023-01-01", "", "lot-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "90", "2023-01-20", "", "lot-b"); err != nil {
+		t.Fatal(err)
+	}
+	// FIFO sells lot-a (basis 100) at 50: a $50 loss, with lot-b (bought
+	// 5 days earlier, inside the 30-day window) as the replacement.
+	if err := db.Sell("BTC", "1", "50", "2023-01-25", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	events := db.TaxEvents["2023-01-25"]
+	if len(events) != 1 {
+		t.Fatalf("got %d tax events, want 1: %+v", len(events), events)
+	}
+	event := events[0]
+	if event.DisallowedLoss == nil || event.DisallowedLoss.Cmp(mustRat(t, "50")) != 0 {
+		t.Fatalf("got DisallowedLoss %v, want 50", event.DisallowedLoss)
+	}
+	if event.ReplacementLotID != "lot-b" {
+		t.Fatalf("got ReplacementLotID %q, want lot-b", event.ReplacementLotID)
+	}
+
+	var replacement *Holding
+	for _, h := range db.Holdings {
+		if h.ID == "lot-b" {
+			replacement = h
+		}
+	}
+	if replacement == nil {
+		t.Fatal("replacement lot-b is gone from db.Holdings")
+	}
+	if replacement.CostBasisPerUnitInUSD.Cmp(mustRat(t, "140")) != 0 {
+		t.Fatalf("got replacement basis %s, want 140 (90 + 50 disallowed loss / 1 unit)",
+			replacement.CostBasisPerUnitInUSD.FloatString(8))
+	}
+}
+
+// TestWashSaleDisallowsLossAgainstReplacementBoughtAfterSale covers
+// checkWashSaleOnBuy's path: the loss sale happens first, leaving it
+// pending, and a later Buy within the window supplies the replacement.
+func TestWashSaleDisallowsLossAgainstReplacementBoughtAfterSale(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2023-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "60", "2023-01-10", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "65", "2023-01-15", "", "lot-c"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := db.TaxEvents["2023-01-10"]
+	if len(events) != 1 {
+		t.Fatalf("got %d tax events, want 1: %+v", len(events), events)
+	}
+	event := events[0]
+	if event.DisallowedLoss == nil || event.DisallowedLoss.Cmp(mustRat(t, "40")) != 0 {
+		t.Fatalf("got DisallowedLoss %v, want 40", event.DisallowedLoss)
+	}
+	if event.ReplacementLotID != "lot-c" {
+		t.Fatalf("got ReplacementLotID %q, want lot-c", event.ReplacementLotID)
+	}
+	if len(db.pendingWashSales) != 0 {
+		t.Fatalf("expected the pending wash sale to be resolved, got %d left", len(db.pendingWashSales))
+	}
+}
+
+// TestWashSaleWindowExpiredLeavesLossAllowed covers a loss sale with no
+// replacement purchase within WashSaleWindow on either side: the loss
+// stands and is left pending (or, if never matched, simply unclaimed).
+func TestWashSaleWindowExpiredLeavesLossAllowed(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2023-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "60", "2023-06-01", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Buy("BTC", "1", "65", "2023-12-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	event := db.TaxEvents["2023-06-01"][0]
+	if event.DisallowedLoss != nil {
+		t.Fatalf("got DisallowedLoss %v, want nil (replacement is outside the wash-sale window)", event.DisallowedLoss)
+	}
+}
+
+// TestWashSaleIgnoresGains confirms a sale at a gain never parks a
+// pending wash-sale entry, since §1091 only disallows losses.
+func TestWashSaleIgnoresGains(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "1", "100", "2023-01-01", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "1", "150", "2023-01-10", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.pendingWashSales) != 0 {
+		t.Fatalf("expected no pending wash sales after a gain, got %d", len(db.pendingWashSales))
+	}
+	if db.TaxEvents["2023-01-10"][0].DisallowedLoss != nil {
+		t.Fatal("a sale at a gain should never have a DisallowedLoss")
+	}
+}
+
+// TestWashSalePartialSaleDoesNotMatchItsOwnLeftoverLot is a regression
+// test: selling part of a lot at a loss leaves the rest of that same lot
+// sitting in h.Holdings with an AcquisitionDate before the sale, which
+// must not be mistaken for a genuine replacement purchase.
+func TestWashSalePartialSaleDoesNotMatchItsOwnLeftoverLot(t *testing.T) {
+	db := NewHoldingDB()
+	if _, err := db.Buy("BTC", "10", "100", "2023-01-01", "", "lot-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sell("BTC", "3", "50", "2023-01-10", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	event := db.TaxEvents["2023-01-10"][0]
+	if event.DisallowedLoss != nil {
+		t.Fatalf("got DisallowedLoss %v, want nil (no replacement lot was ever bought)", event.DisallowedLoss)
+	}
+	if event.ReplacementLotID != "" {
+		t.Fatalf("got ReplacementLotID %q, want empty", event.ReplacementLotID)
+	}
+
+	var remainder *Holding
+	for _, h := range db.Holdings {
+		if h.ID == "lot-a" {
+			remainder = h
+		}
+	}
+	if remainder == nil {
+		t.Fatal("lot-a should still have 7 units left")
+	}
+	if remainder.CostBasisPerUnitInUSD.Cmp(mustRat(t, "100")) != 0 {
+		t.Fatalf("got leftover basis %s, want 100 (unchanged by the partial sale)",
+			remainder.CostBasisPerUnitInUSD.FloatString(8))
+	}
+}